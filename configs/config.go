@@ -1,14 +1,27 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
 )
 
+// AfricaTalkingConfig configures the Africa's Talking SMS API.
+// CallbackToken must be passed as ?token= on the delivery report callback
+// URL registered with Africa's Talking, since their callbacks are not
+// signed. It may only be left empty when APP_ENV=development.
 type AfricaTalkingConfig struct {
-	Username string
-	APIKey   string
-	SMSURL   string 
-	SenderID string 
+	Username      string
+	APIKey        string
+	SMSURL        string
+	SenderID      string
+	CallbackToken string
 }
 
 type EmailConfig struct {
@@ -18,12 +31,126 @@ type EmailConfig struct {
 	SenderEmail        string
 }
 
+// SMTPConfig describes an SMTP relay. TLSMode is "starttls" (upgrade a
+// plain connection, usually port 587), "tls" (implicit TLS, usually port
+// 465) or "none". AuthMechanism is "plain", "login" or "none".
+type SMTPConfig struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	From          string
+	TLSMode       string
+	AuthMechanism string
+}
+
+// NotifierConfig selects the SMS and email providers: "africastalking",
+// "ses" or "smtp" in production, "log" for local development, "memory" for
+// tests. TemplateDir optionally points at a directory of templates
+// overriding the built-in ones, and DefaultLocale is used for customers
+// whose own locale has no template. PublicBaseURL and UnsubscribeSecret are
+// used to build the signed unsubscribe links in emails; without
+// UNSUBSCRIBE_SECRET the secret is derived from SESSION_SECRET.
+type NotifierConfig struct {
+	SMSProvider       string
+	EmailProvider     string
+	TemplateDir       string
+	DefaultLocale     string
+	PublicBaseURL     string
+	UnsubscribeSecret string
+}
+
+// OutboxConfig tunes the notification outbox worker. The delay before
+// retry n is BaseBackoff doubled n-1 times, capped at MaxBackoff.
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// AuthConfig controls the sign-in flow. ReturnToAllowList holds the path
+// prefixes (e.g. "/account") and origins (e.g. "https://shop.example.com")
+// that a login's return_to may point at; LoginTimeout is how long a user
+// has to come back from the identity provider. PostLogoutRedirectURL is
+// where the provider sends the user after signing them out there too.
+// BootstrapAdmins are emails made admins at startup, so a new install has
+// someone who can grant staff roles; it defaults to the ADMIN_EMAILS that
+// used to gate the admin endpoints. RoleMappings maps values of the ID
+// token claim named by RoleClaim (a dotted path such as
+// "realm_access.roles" reaches into nested claims) to staff roles, e.g.
+// AUTH_ROLE_MAPPINGS=shop-admins=admin,catalog=catalog-manager. The
+// identity providers themselves are read by LoadOIDCProviders.
+type AuthConfig struct {
+	ReturnToAllowList     []string
+	LoginTimeout          time.Duration
+	PostLogoutRedirectURL string
+	BootstrapAdmins       []string
+	RoleClaim             string
+	RoleMappings          map[string]string
+}
+
+// DefaultOIDCProvider names the provider configured by the unprefixed
+// OIDC_* variables when AUTH_PROVIDERS is not set.
+const DefaultOIDCProvider = "default"
+
+// OIDCProviderConfig is one OpenID Connect provider users can sign in
+// with. Providers listed in AUTH_PROVIDERS (e.g. "google,keycloak") are
+// read from OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and
+// OIDC_<NAME>_API_AUDIENCE, with dashes in the name written as
+// underscores. APIAudience is the "aud" bearer access tokens must carry;
+// it defaults to AUTH_API_AUDIENCE. Bearer tokens from the provider are
+// only accepted once it is set, and it must not be the client ID, which is
+// the audience of ID tokens. MapRoles lets
+// the provider's group claims grant staff roles (OIDC_<NAME>_MAP_ROLES),
+// and lets a verified email claim bind a staff user to their account at
+// the provider on first sign-in; only turn it on for providers whose
+// groups and emails you control. TrustEmail (OIDC_<NAME>_TRUST_EMAIL) lets
+// a first login with a verified email sign in as the customer who already
+// has that email; otherwise such a login is refused and the customer has to
+// link the provider while signed in.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	APIAudience  string
+	MapRoles     bool
+	TrustEmail   bool
+}
+
+// DefaultSessionSecret is the placeholder SESSION_SECRET used when none
+// is set. It is only accepted in development.
+const DefaultSessionSecret = "change-me"
+
+// SessionConfig controls browser sessions. Env is the deployment
+// environment (APP_ENV); anything other than "development" or "dev" is
+// treated as production.
+type SessionConfig struct {
+	Env    string
+	Secret string
+	MaxAge time.Duration
+}
+
+// IsDevelopment reports whether the app is running in development mode.
+func (c SessionConfig) IsDevelopment() bool {
+	switch strings.ToLower(c.Env) {
+	case "development", "dev":
+		return true
+	}
+	return false
+}
+
 func LoadAfricaTalkingConfig() AfricaTalkingConfig {
 	return AfricaTalkingConfig{
-		Username: os.Getenv("AT_USERNAME"),
-		APIKey:   os.Getenv("AT_API_KEY"),
-		SMSURL:   getEnvOrDefault("AT_SMS_URL", "https://api.sandbox.africastalking.com/version1/messaging"), // Sandbox URL
-		SenderID: getEnvOrDefault("AT_SENDER_ID", "AFRICASTKNG"), // Default sandbox sender ID
+		Username:      os.Getenv("AT_USERNAME"),
+		APIKey:        os.Getenv("AT_API_KEY"),
+		SMSURL:        getEnvOrDefault("AT_SMS_URL", "https://api.sandbox.africastalking.com/version1/messaging"), // Sandbox URL
+		SenderID:      getEnvOrDefault("AT_SENDER_ID", "AFRICASTKNG"),                                             // Default sandbox sender ID
+		CallbackToken: os.Getenv("AT_CALLBACK_TOKEN"),
 	}
 }
 
@@ -36,10 +163,177 @@ func LoadEmailConfig() EmailConfig {
 	}
 }
 
+func LoadSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Host:          os.Getenv("SMTP_HOST"),
+		Port:          getIntOrDefault("SMTP_PORT", 587),
+		Username:      os.Getenv("SMTP_USERNAME"),
+		Password:      os.Getenv("SMTP_PASSWORD"),
+		From:          os.Getenv("SMTP_FROM"),
+		TLSMode:       getEnvOrDefault("SMTP_TLS", "starttls"),
+		AuthMechanism: getEnvOrDefault("SMTP_AUTH", "plain"),
+	}
+}
+
+func LoadNotifierConfig() NotifierConfig {
+	return NotifierConfig{
+		SMSProvider:       getEnvOrDefault("SMS_PROVIDER", "africastalking"),
+		EmailProvider:     getEnvOrDefault("EMAIL_PROVIDER", "ses"),
+		TemplateDir:       os.Getenv("NOTIFIER_TEMPLATE_DIR"),
+		DefaultLocale:     getEnvOrDefault("NOTIFIER_DEFAULT_LOCALE", "en"),
+		PublicBaseURL:     getEnvOrDefault("PUBLIC_BASE_URL", "http://localhost:8080"),
+		UnsubscribeSecret: getEnvOrDefault("UNSUBSCRIBE_SECRET", deriveSecret(os.Getenv("SESSION_SECRET"), "unsubscribe-links")),
+	}
+}
+
+// deriveSecret derives a key for one purpose from secret with HKDF-SHA256,
+// so links work out of the box without ever signing them with the session
+// secret itself. It returns "" when secret is empty.
+func deriveSecret(secret, purpose string) string {
+	if secret == "" {
+		return ""
+	}
+
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(purpose)), key); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+func LoadOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		PollInterval: getDurationOrDefault("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		BatchSize:    getIntOrDefault("OUTBOX_BATCH_SIZE", 20),
+		MaxAttempts:  getIntOrDefault("OUTBOX_MAX_ATTEMPTS", 8),
+		BaseBackoff:  getDurationOrDefault("OUTBOX_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   getDurationOrDefault("OUTBOX_MAX_BACKOFF", time.Hour),
+	}
+}
+
+func LoadAuthConfig() AuthConfig {
+	return AuthConfig{
+		ReturnToAllowList:     getListOrDefault("AUTH_RETURN_TO_ALLOWLIST", []string{"/"}),
+		LoginTimeout:          getDurationOrDefault("AUTH_LOGIN_TIMEOUT", 10*time.Minute),
+		PostLogoutRedirectURL: os.Getenv("OIDC_POST_LOGOUT_REDIRECT_URL"),
+		BootstrapAdmins:       getListOrDefault("AUTH_BOOTSTRAP_ADMINS", getListOrDefault("ADMIN_EMAILS", nil)),
+		RoleClaim:             getEnvOrDefault("AUTH_ROLE_CLAIM", "groups"),
+		RoleMappings:          getMapOrDefault("AUTH_ROLE_MAPPINGS", nil),
+	}
+}
+
+// LoadOIDCProviders returns the providers named in AUTH_PROVIDERS, in
+// order. Without AUTH_PROVIDERS it returns the single provider configured
+// by OIDC_ISSUER, OIDC_CLIENT_ID and friends, named DefaultOIDCProvider,
+// which may map roles.
+func LoadOIDCProviders() []OIDCProviderConfig {
+	names := getListOrDefault("AUTH_PROVIDERS", nil)
+	if len(names) == 0 {
+		return []OIDCProviderConfig{{
+			Name:         DefaultOIDCProvider,
+			Issuer:       os.Getenv("OIDC_ISSUER"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			APIAudience:  os.Getenv("AUTH_API_AUDIENCE"),
+			MapRoles:     true,
+			TrustEmail:   getBoolOrDefault("OIDC_TRUST_EMAIL", false),
+		}}
+	}
+
+	providers := make([]OIDCProviderConfig, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			APIAudience:  getEnvOrDefault(prefix+"API_AUDIENCE", os.Getenv("AUTH_API_AUDIENCE")),
+			MapRoles:     getBoolOrDefault(prefix+"MAP_ROLES", false),
+			TrustEmail:   getBoolOrDefault(prefix+"TRUST_EMAIL", false),
+		})
+	}
+	return providers
+}
+
+func LoadSessionConfig() SessionConfig {
+	return SessionConfig{
+		Env:    getEnvOrDefault("APP_ENV", "production"),
+		Secret: getEnvOrDefault("SESSION_SECRET", DefaultSessionSecret),
+		MaxAge: getDurationOrDefault("SESSION_MAX_AGE", 7*24*time.Hour),
+	}
+}
+
+// LoadDefaultPhoneCountry returns the ISO 3166 country assumed for phone
+// numbers written without a country code.
+func LoadDefaultPhoneCountry() string {
+	return strings.ToUpper(getEnvOrDefault("PHONE_DEFAULT_COUNTRY", "KE"))
+}
+
+// LoadInitialStock returns PRODUCT_INITIAL_STOCK, the stock given to
+// products that existed before stock was tracked. It defaults to 0.
+func LoadInitialStock() int {
+	return getIntOrDefault("PRODUCT_INITIAL_STOCK", 0)
+}
 
 func getEnvOrDefault(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getIntOrDefault(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func getBoolOrDefault(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getListOrDefault splits a comma-separated variable, dropping blanks.
+func getListOrDefault(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// getMapOrDefault reads comma-separated key=value pairs. Pairs without a
+// key or value are ignored.
+func getMapOrDefault(key string, defaultValue map[string]string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getListOrDefault(key, nil) {
+		k, v, ok := strings.Cut(pair, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if ok && k != "" && v != "" {
+			values[k] = v
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
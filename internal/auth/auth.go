@@ -2,44 +2,53 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/oauth2"
 
+	"github.com/Keoroanthony/go-ecommerce/configs"
 	"github.com/Keoroanthony/go-ecommerce/internal/db"
 	"github.com/Keoroanthony/go-ecommerce/internal/models"
 )
 
 var (
-	provider     *oidc.Provider
-	verifier     *oidc.IDTokenVerifier
-	oauth2Config *oauth2.Config
+	defaultPhoneCountry   = models.DefaultPhoneCountry
+	returnToAllowList     = []string{"/"}
+	loginTimeout          = 10 * time.Minute
+	postLogoutRedirectURL string
 )
 
 const sessionName = "gosess"
 
 func Init() {
-	ctx := context.Background()
-
-	var err error
-	provider, err = oidc.NewProvider(ctx, os.Getenv("OIDC_ISSUER"))
-	if err != nil {
+	if err := initProviders(context.Background(), config.LoadOIDCProviders()); err != nil {
 		log.Fatalf("OIDC provider init error: %v", err)
 	}
 
-	verifier = provider.Verifier(&oidc.Config{ClientID: os.Getenv("OIDC_CLIENT_ID")})
+	defaultPhoneCountry = config.LoadDefaultPhoneCountry()
+
+	authCfg := config.LoadAuthConfig()
+	returnToAllowList = authCfg.ReturnToAllowList
+	loginTimeout = authCfg.LoginTimeout
+	postLogoutRedirectURL = authCfg.PostLogoutRedirectURL
+	roleClaim = authCfg.RoleClaim
+	roleMappings = authCfg.RoleMappings
+	for group, role := range roleMappings {
+		if !models.IsValidRole(role) {
+			log.Fatalf("AUTH_ROLE_MAPPINGS maps %q to unknown role %q", group, role)
+		}
+	}
 
-	oauth2Config = &oauth2.Config{
-		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
-		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
-		Endpoint:     provider.Endpoint(),
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email", "phone"},
+	if err := SeedAdmins(authCfg.BootstrapAdmins); err != nil {
+		log.Fatalf("Seeding admins failed: %v", err)
 	}
 }
 
@@ -47,15 +56,73 @@ func Init() {
 // Handlers
 // ─────────────────────────────────────────────────────────────────────────────
 
-// GET /auth/login
+// GET /auth/login/:provider
+//
+// Starts an authorization code flow with PKCE (S256) at the named
+// provider, or the default provider when there is no :provider. A random
+// state and nonce are kept in the session and checked by Callback. The
+// optional ?return_to= must be on the allow-list; the user is sent there
+// once signed in.
 func Login(c *gin.Context) {
-	state := "rand" // TODO: generate & store real CSRF-safe state if needed
-	url := oauth2Config.AuthCodeURL(state)
-	c.Redirect(http.StatusFound, url)
+	p, ok := lookupProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return
+	}
+
+	returnTo := c.Query("return_to")
+	if returnTo != "" && !allowedReturnTo(returnTo) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "return_to is not allowed"})
+		return
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start login"})
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start login"})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	login := pendingLogin{Provider: p.name, State: state, Nonce: nonce, Verifier: verifier, ReturnTo: returnTo, StartedAt: time.Now()}
+	if err := savePendingLogin(sessions.Default(c), login); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start login"})
+		return
+	}
+
+	authURL := p.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	c.Redirect(http.StatusFound, authURL)
 }
 
-// GET /auth/callback
+// GET /auth/callback/:provider
+//
+// Completes a login started by Login. The login is linked to a customer
+// through its issuer and subject; see customerForLogin.
 func Callback(c *gin.Context) {
+	p, found := lookupProvider(c.Param("provider"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return
+	}
+
+	sess := sessions.Default(c)
+	login, ok := takePendingLogin(sess)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "login failed: " + providerErr})
+		return
+	}
+
+	// A response must come back from the provider the login went to.
+	if !ok || login.Provider != p.name || !login.matches(c.Query("state"), time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login state"})
+		return
+	}
+
 	code := c.Query("code")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "code missing"})
@@ -63,7 +130,7 @@ func Callback(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	oauth2Token, err := oauth2Config.Exchange(ctx, code)
+	oauth2Token, err := p.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "token exchange failed"})
 		return
@@ -75,61 +142,197 @@ func Callback(c *gin.Context) {
 		return
 	}
 
-	idToken, err := verifier.Verify(ctx, rawIDToken)
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "token verification failed"})
 		return
 	}
 
-	// Extract claims
-	var claims struct {
-		Sub   string `json:"sub"`
-		Name  string `json:"name"`
-		Email string `json:"email"`
-		Phone string `json:"phone_number"`
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(login.Nonce)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token nonce mismatch"})
+		return
 	}
+
+	// Extract claims
+	var claims loginClaims
 	if err := idToken.Claims(&claims); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "claims parse error"})
 		return
 	}
 
-	// Upsert customer
-	var cust models.Customer
-	if err := db.DB.Where("o_id_c_id = ?", claims.Sub).First(&cust).Error; err != nil {
-		cust = models.Customer{
-			OIDCID: claims.Sub,
-			Name:   claims.Name,
-			Email:  claims.Email,
-			Phone:  claims.Phone,
+	// Staff whose IdP groups map to a role sign in as staff only, with the
+	// role the IdP gives them today. Only providers trusted to map roles
+	// are asked.
+	var mappedStaff *models.User
+	if p.mapRoles {
+		mappedStaff, err = syncMappedStaff(idToken, claims.Email, claims.Name, bool(claims.EmailVerified))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "staff sync failed"})
+			return
+		}
+	}
+	if mappedStaff != nil {
+		sess.Delete("customer_id")
+		sess.Set("user_id", mappedStaff.ID)
+		sess.Set(sessionKeyProvider, p.name)
+		_ = sess.Save()
+
+		finishLogin(c, login, gin.H{"message": "logged in", "user": mappedStaff})
+		return
+	}
+
+	// An unusable phone number is dropped rather than failing the login;
+	// the customer can add a valid one through their profile.
+	phone := ""
+	if claims.Phone != "" {
+		normalized, err := models.NormalizePhone(claims.Phone, defaultPhoneCountry)
+		if err != nil {
+			log.Printf("Ignoring phone_number claim for %s: %v", claims.Sub, err)
+		} else {
+			phone = normalized
 		}
-		db.DB.Create(&cust)
+	}
+
+	// A customer who is already signed in is linking another login.
+	signedIn, _ := sess.Get("customer_id").(uint)
+
+	cust, err := customerForLogin(p, idToken.Issuer, claims, phone, signedIn)
+	if errors.Is(err, errEmailInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": "an account with this email already exists; sign in with the provider you used before, then sign in here to link this one"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "customer sign-in failed"})
+		return
+	}
+
+	// Staff sign in through the same providers: the identity bound to a
+	// staff user, or the first verified login for their email at a trusted
+	// provider, also starts their staff session.
+	staff, err := linkStaffUser(p, claims.Sub, claims.Email, bool(claims.EmailVerified))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "staff lookup failed"})
+		return
 	}
 
 	// Store customer-ID in session
-	sess := sessions.Default(c)
 	sess.Set("customer_id", cust.ID)
+	sess.Set(sessionKeyProvider, p.name)
+	if staff != nil {
+		sess.Set("user_id", staff.ID)
+	} else {
+		sess.Delete("user_id")
+	}
 	_ = sess.Save()
 
-	c.JSON(http.StatusOK, gin.H{"message": "logged in", "customer": cust})
+	response := gin.H{"message": "logged in", "customer": cust}
+	if staff != nil {
+		response["user"] = staff
+	}
+	finishLogin(c, login, response)
+}
+
+// finishLogin sends the user back to where they started, or responds with
+// body when they did not say.
+func finishLogin(c *gin.Context, login pendingLogin, body gin.H) {
+	if login.ReturnTo != "" {
+		c.Redirect(http.StatusFound, login.ReturnTo)
+		return
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// POST /auth/logout
+//
+// Ends the session. When the provider the user signed in with supports
+// RP-initiated logout the user is also sent there (303) to end their
+// provider session; otherwise the response is JSON.
+func Logout(c *gin.Context) {
+	sess := sessions.Default(c)
+	providerName, _ := sess.Get(sessionKeyProvider).(string)
+
+	sess.Clear()
+	sess.Options(sessions.Options{Path: "/", MaxAge: -1})
+	if err := sess.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to end session"})
+		return
+	}
+
+	// Sessions from before providers were recorded belong to the default.
+	p, ok := lookupProvider(providerName)
+	if !ok || p.endSessionEndpoint == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+		return
+	}
+
+	logoutURL, err := url.Parse(p.endSessionEndpoint)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+		return
+	}
+	q := logoutURL.Query()
+	q.Set("client_id", p.oauth2Config.ClientID)
+	if postLogoutRedirectURL != "" {
+		q.Set("post_logout_redirect_uri", postLogoutRedirectURL)
+	}
+	logoutURL.RawQuery = q.Encode()
+
+	c.Redirect(http.StatusSeeOther, logoutURL.String())
 }
 
-// Middleware: ensures user is logged in and injects *models.Customer into context.
+// Middleware: ensures someone is logged in, either with a session cookie,
+// an "Authorization: Bearer" access token or an X-API-Key machine key. The
+// signed-in customer, if any, is put on the context as "customer"
+// (*models.Customer), the staff user, if any, as "user" (*models.User) and
+// the API key as "api_key" (*models.APIKey). A request carrying a token or
+// key is judged on that alone; API keys must also hold the scope declared
+// for the route with SetAPIKeyScopes.
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			if authenticateAPIKey(c, key) {
+				c.Next()
+			}
+			return
+		}
+
+		if header := c.GetHeader("Authorization"); header != "" {
+			if authenticateBearer(c, header) {
+				c.Next()
+			}
+			return
+		}
+
 		sess := sessions.Default(c)
-		custID, ok := sess.Get("customer_id").(uint)
-		if !ok || custID == 0 {
+		custID, _ := sess.Get("customer_id").(uint)
+		userID, _ := sess.Get("user_id").(uint)
+		if custID == 0 && userID == 0 {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
 
-		var cust models.Customer
-		if err := db.DB.First(&cust, custID).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
-			return
+		if custID != 0 {
+			var cust models.Customer
+			if err := db.DB.First(&cust, custID).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+				return
+			}
+			// put on context for handlers
+			c.Set("customer", &cust)
 		}
-		// put on context for handlers
-		c.Set("customer", &cust)
+
+		// A removed staff user loses staff rights straight away.
+		if userID != 0 {
+			var user models.User
+			if err := db.DB.First(&user, userID).Error; err == nil {
+				c.Set("user", &user)
+			} else if custID == 0 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+				return
+			}
+		}
+
 		c.Next()
 	}
 }
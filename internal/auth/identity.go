@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// loginClaims are the ID token claims a login reads.
+type loginClaims struct {
+	Sub    string `json:"sub"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Phone  string `json:"phone_number"`
+	Locale string `json:"locale"`
+
+	EmailVerified claimBool `json:"email_verified"`
+}
+
+// errEmailInUse stops a new login from taking over the customer with the
+// same email unless the provider is trusted to have verified it.
+var errEmailInUse = errors.New("email belongs to another customer")
+
+// customerForLogin returns the customer a login signs in. Logins are told
+// apart by issuer and subject, since a subject is only unique within its
+// issuer. The first time a login is seen it is linked:
+//   - to signedIn, the customer already signed in, if any, who is linking
+//     another provider;
+//   - otherwise to the customer with the same email, if the provider is
+//     trusted with emails and verified it, or else it is refused with
+//     errEmailInUse;
+//   - otherwise to a new customer.
+//
+// phone fills in a missing phone number.
+func customerForLogin(p *identityProvider, issuer string, claims loginClaims, phone string, signedIn uint) (models.Customer, error) {
+	var cust models.Customer
+	now := time.Now()
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.CustomerIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, claims.Sub).First(&identity).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&cust, identity.CustomerID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": now}).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := findOrCreateCustomer(tx, p, &cust, claims, phone, signedIn); err != nil {
+				return err
+			}
+			identity = models.CustomerIdentity{
+				CustomerID:  cust.ID,
+				Provider:    p.name,
+				Issuer:      issuer,
+				Subject:     claims.Sub,
+				Email:       claims.Email,
+				LastLoginAt: &now,
+			}
+			if err := tx.Create(&identity).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if cust.Phone == "" && phone != "" {
+			cust.Phone = phone
+			return tx.Model(&cust).Update("phone", phone).Error
+		}
+		return nil
+	})
+
+	return cust, err
+}
+
+// findOrCreateCustomer loads into cust the customer a new login should be
+// linked to, creating one if there is none.
+func findOrCreateCustomer(tx *gorm.DB, p *identityProvider, cust *models.Customer, claims loginClaims, phone string, signedIn uint) error {
+	if signedIn != 0 {
+		err := tx.First(cust, signedIn).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if claims.Email != "" {
+		err := tx.Where("LOWER(email) = ?", strings.ToLower(claims.Email)).First(cust).Error
+		switch {
+		case err == nil && (!p.trustEmail || !bool(claims.EmailVerified)):
+			return errEmailInUse
+		case err == nil:
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	*cust = models.Customer{
+		Name:   claims.Name,
+		Email:  claims.Email,
+		Phone:  phone,
+		Locale: claims.Locale,
+	}
+	return tx.Create(cust).Error
+}
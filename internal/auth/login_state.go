@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+)
+
+// Session keys holding an in-flight login between Login and Callback.
+const (
+	sessionKeyLoginWith = "oidc_login_provider"
+	sessionKeyState     = "oidc_state"
+	sessionKeyNonce     = "oidc_nonce"
+	sessionKeyVerifier  = "oidc_verifier"
+	sessionKeyReturnTo  = "oidc_return_to"
+	sessionKeyStartedAt = "oidc_started_at"
+)
+
+// pendingLogin is what Login remembers so Callback can check that the
+// response belongs to a login this browser started.
+type pendingLogin struct {
+	Provider  string
+	State     string
+	Nonce     string
+	Verifier  string
+	ReturnTo  string
+	StartedAt time.Time
+}
+
+// randomToken returns 32 random bytes, base64url-encoded.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func savePendingLogin(sess sessions.Session, login pendingLogin) error {
+	sess.Set(sessionKeyLoginWith, login.Provider)
+	sess.Set(sessionKeyState, login.State)
+	sess.Set(sessionKeyNonce, login.Nonce)
+	sess.Set(sessionKeyVerifier, login.Verifier)
+	sess.Set(sessionKeyReturnTo, login.ReturnTo)
+	sess.Set(sessionKeyStartedAt, login.StartedAt.Unix())
+	return sess.Save()
+}
+
+// takePendingLogin reads and forgets the in-flight login, so each state
+// can be used once. It reports false when there is none.
+func takePendingLogin(sess sessions.Session) (pendingLogin, bool) {
+	provider, _ := sess.Get(sessionKeyLoginWith).(string)
+	state, _ := sess.Get(sessionKeyState).(string)
+	nonce, _ := sess.Get(sessionKeyNonce).(string)
+	verifier, _ := sess.Get(sessionKeyVerifier).(string)
+	returnTo, _ := sess.Get(sessionKeyReturnTo).(string)
+	startedAt, _ := sess.Get(sessionKeyStartedAt).(int64)
+
+	for _, key := range []string{sessionKeyLoginWith, sessionKeyState, sessionKeyNonce, sessionKeyVerifier, sessionKeyReturnTo, sessionKeyStartedAt} {
+		sess.Delete(key)
+	}
+	_ = sess.Save()
+
+	if state == "" || nonce == "" || verifier == "" {
+		return pendingLogin{}, false
+	}
+
+	return pendingLogin{
+		Provider:  provider,
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ReturnTo:  returnTo,
+		StartedAt: time.Unix(startedAt, 0),
+	}, true
+}
+
+// matches reports whether state was issued for this login and the login
+// has not timed out.
+func (l pendingLogin) matches(state string, now time.Time) bool {
+	if subtle.ConstantTimeCompare([]byte(state), []byte(l.State)) != 1 {
+		return false
+	}
+	return now.Sub(l.StartedAt) <= loginTimeout
+}
+
+// allowedReturnTo reports whether target is a safe place to send the user
+// after login: a local path under one of the allowed path prefixes, or an
+// absolute URL on one of the allowed origins.
+func allowedReturnTo(target string) bool {
+	if target == "" || strings.ContainsAny(target, "\\\r\n\t") {
+		return false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil {
+		return false
+	}
+
+	// Browsers read "//host/path" as another host, so a local path must
+	// start with exactly one slash.
+	local := u.Scheme == "" && u.Host == "" && strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+
+	for _, allowed := range returnToAllowList {
+		if strings.HasPrefix(allowed, "/") {
+			if local && underPath(u.Path, allowed) {
+				return true
+			}
+			continue
+		}
+
+		origin, err := url.Parse(allowed)
+		if err != nil || local {
+			continue
+		}
+		if strings.EqualFold(u.Scheme, origin.Scheme) && strings.EqualFold(u.Host, origin.Host) && underPath(u.Path, origin.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// underPath reports whether p is prefix or below it, after resolving any
+// dot segments.
+func underPath(p, prefix string) bool {
+	p = path.Clean("/" + p)
+	prefix = path.Clean("/" + prefix)
+	return prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+)
+
+// identityProvider is an OpenID Connect provider users can sign in with.
+type identityProvider struct {
+	name       string
+	issuer     string
+	mapRoles   bool
+	trustEmail bool
+
+	verifier       *oidc.IDTokenVerifier
+	accessVerifier *oidc.IDTokenVerifier
+	oauth2Config   *oauth2.Config
+
+	// endSessionEndpoint is the provider's RP-initiated logout URL, if its
+	// discovery document has one.
+	endSessionEndpoint string
+}
+
+// providers holds the configured providers by name; providerNames keeps
+// their configured order, and the first is the default used by
+// /auth/login without a provider.
+var (
+	providers     = map[string]*identityProvider{}
+	providerNames []string
+)
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// sessionKeyProvider records which provider the session signed in with,
+// so Logout can end the session there too.
+const sessionKeyProvider = "oidc_provider"
+
+// initProviders discovers each configured provider. Names must be usable
+// in URLs and issuers distinct, since logins are told apart by issuer.
+func initProviders(ctx context.Context, cfgs []config.OIDCProviderConfig) error {
+	loaded := make(map[string]*identityProvider, len(cfgs))
+	names := make([]string, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		if !providerNamePattern.MatchString(cfg.Name) {
+			return fmt.Errorf("invalid provider name %q", cfg.Name)
+		}
+		if _, dup := loaded[cfg.Name]; dup {
+			return fmt.Errorf("provider %q configured twice", cfg.Name)
+		}
+		for _, other := range loaded {
+			if other.issuer == cfg.Issuer {
+				return fmt.Errorf("providers %q and %q share issuer %s", other.name, cfg.Name, cfg.Issuer)
+			}
+		}
+
+		p, err := newIdentityProvider(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("provider %q: %w", cfg.Name, err)
+		}
+		loaded[cfg.Name] = p
+		names = append(names, cfg.Name)
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no identity providers configured")
+	}
+
+	providers, providerNames = loaded, names
+	return nil
+}
+
+func newIdentityProvider(ctx context.Context, cfg config.OIDCProviderConfig) (*identityProvider, error) {
+	if cfg.APIAudience != "" && cfg.APIAudience == cfg.ClientID {
+		return nil, fmt.Errorf("API audience must not be the client ID, or ID tokens would pass as access tokens")
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("parsing discovery document: %w", err)
+	}
+
+	// Without an API audience of its own the provider only signs people
+	// in; its bearer tokens are refused.
+	var accessVerifier *oidc.IDTokenVerifier
+	if cfg.APIAudience != "" {
+		accessVerifier = provider.Verifier(&oidc.Config{ClientID: cfg.APIAudience})
+	}
+
+	return &identityProvider{
+		name:           cfg.Name,
+		issuer:         cfg.Issuer,
+		mapRoles:       cfg.MapRoles,
+		trustEmail:     cfg.TrustEmail,
+		verifier:       provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		accessVerifier: accessVerifier,
+		oauth2Config: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email", "phone"},
+		},
+		endSessionEndpoint: discovery.EndSessionEndpoint,
+	}, nil
+}
+
+// lookupProvider returns the provider called name, or the default
+// provider when name is empty.
+func lookupProvider(name string) (*identityProvider, bool) {
+	if name == "" && len(providerNames) > 0 {
+		name = providerNames[0]
+	}
+	p, ok := providers[name]
+	return p, ok
+}
+
+// providerForIssuer returns the provider that issues tokens as issuer.
+func providerForIssuer(issuer string) (*identityProvider, bool) {
+	for _, p := range providers {
+		if p.issuer == issuer {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// unverifiedIssuer reads the "iss" claim of a JWT without checking it, to
+// pick the provider whose keys can then verify the token.
+func unverifiedIssuer(rawToken string) (string, bool) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Issuer == "" {
+		return "", false
+	}
+	return claims.Issuer, true
+}
+
+// GET /auth/providers
+//
+// Lists the providers users can sign in with, so a login page can offer a
+// button for each.
+func ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": providerNames})
+}
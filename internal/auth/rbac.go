@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// CurrentUser returns the staff user RequireAuth put on the context.
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, ok := c.Get("user")
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok && user != nil
+}
+
+// RequireRole lets the request through only for staff holding one of
+// roles; admins always pass. It must run after RequireAuth. API keys are
+// let through: RequireAuth has already checked them against the route's
+// scope.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := CurrentAPIKey(c); ok {
+			c.Next()
+			return
+		}
+
+		user, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "staff access required"})
+			return
+		}
+
+		if !user.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "required_roles": roles})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SeedAdmins makes sure each of emails belongs to an admin, creating the
+// staff user or promoting it as needed.
+func SeedAdmins(emails []string) error {
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+
+		var user models.User
+		err := db.DB.Where("email = ?", email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			user = models.User{Name: email, Email: email, Role: models.RoleAdmin}
+			if err := db.DB.Create(&user).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case user.Role != models.RoleAdmin:
+			if err := db.DB.Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// linkStaffUser returns the staff user signing in as subject at p, or nil
+// when there is none. A user already bound to an OIDC identity is only
+// found through it. An unbound user is bound on their first sign-in with a
+// verified email, but only at providers trusted with staff roles, so
+// anyone who can get another provider to vouch for the address cannot
+// sign in as them; a user bound to another identity is never rebound.
+func linkStaffUser(p *identityProvider, subject, email string, verified bool) (*models.User, error) {
+	if subject == "" {
+		return nil, nil
+	}
+
+	var user models.User
+	err := db.DB.Where("issuer = ? AND subject = ?", p.issuer, subject).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !verified || !p.mapRoles {
+		return nil, nil
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).Where("email = ?", email).First(&user).Error; err != nil {
+			return err
+		}
+		if user.Subject != nil {
+			log.Printf("Not signing %s in as staff user %d: already bound to another identity", subject, user.ID)
+			return gorm.ErrRecordNotFound
+		}
+
+		issuer := p.issuer
+		user.Issuer, user.Subject = &issuer, &subject
+		return tx.Model(&user).Updates(map[string]interface{}{"issuer": issuer, "subject": subject}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// claimBool reads a boolean claim that some providers send as a string,
+// e.g. "email_verified": "true".
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseBool(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*b = claimBool(value)
+	return nil
+}
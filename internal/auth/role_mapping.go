@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// roleClaim names the ID token claim holding the user's IdP groups and
+// roleMappings maps those groups to staff roles. With no mappings every
+// login is a customer login, plus any manually granted staff role.
+var (
+	roleClaim    = "groups"
+	roleMappings map[string]string
+)
+
+// mappedRole returns the role the user's groups map to. When groups map
+// to several roles the one listed first in models.Roles wins, so the most
+// privileged mapping applies.
+func mappedRole(groups []string) string {
+	granted := make(map[string]bool)
+	for _, group := range groups {
+		if role, ok := roleMappings[group]; ok {
+			granted[role] = true
+		}
+	}
+
+	for _, role := range models.Roles {
+		if granted[role] {
+			return role
+		}
+	}
+	return ""
+}
+
+// claimValues reads the claim at path, following dots into nested
+// objects. Lists of strings are returned as they are; a single string is
+// split on commas and spaces.
+func claimValues(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return nil
+}
+
+// syncMappedStaff applies the IdP's view of the user's role. The staff
+// user bound to this issuer and subject is updated to the role their
+// groups map to, or removed once they no longer map to one, unless they
+// are the last admin. A login with no bound staff user and a mapped role
+// creates one for its verified email, but never takes over an existing
+// staff user with that email. Users whose role an admin set by hand are
+// left alone whatever their groups say. It returns nil when the login is
+// not a mapped staff login.
+func syncMappedStaff(idToken *oidc.IDToken, email, name string, verified bool) (*models.User, error) {
+	if len(roleMappings) == 0 {
+		return nil, nil
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	role := mappedRole(claimValues(claims, roleClaim))
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if name == "" {
+		name = email
+	}
+	issuer, subject := idToken.Issuer, idToken.Subject
+
+	var (
+		user   models.User
+		mapped bool
+	)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := db.ForUpdate(tx).Where("issuer = ? AND subject = ?", issuer, subject).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if role == "" {
+				return nil
+			}
+			if email == "" || !verified {
+				log.Printf("Not granting %s to %s: no verified email", role, subject)
+				return nil
+			}
+
+			// A staff user who already has this email is either bound to
+			// another identity or was granted by hand and is bound by
+			// linkStaffUser; either way the mapping does not apply.
+			var existing models.User
+			err := tx.Where("email = ?", email).First(&existing).Error
+			if err == nil {
+				if existing.Subject != nil {
+					log.Printf("Not granting %s to %s: staff user %d is already bound to another identity", role, subject, existing.ID)
+				}
+				return nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			user = models.User{Name: name, Email: email, Role: role, RoleSource: models.RoleSourceIdP, Issuer: &issuer, Subject: &subject}
+			mapped = true
+			return tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.RoleSource != models.RoleSourceIdP {
+			return nil
+		}
+		if role != "" {
+			mapped = true
+			return tx.Model(&user).Updates(map[string]interface{}{"name": name, "role": role}).Error
+		}
+
+		if user.Role == models.RoleAdmin {
+			var admins int64
+			if err := tx.Model(&models.User{}).Where("role = ? AND id <> ?", models.RoleAdmin, user.ID).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins == 0 {
+				log.Printf("Not removing staff user %d: they are the last admin", user.ID)
+				return nil
+			}
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil || !mapped {
+		return nil, err
+	}
+
+	return &user, nil
+}
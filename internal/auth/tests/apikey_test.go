@@ -0,0 +1,93 @@
+package auth_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// createAPIKey stores a key with the given scopes and returns it.
+func (a *authTestApp) createAPIKey(t *testing.T, scopes string, modify func(*models.APIKey)) (string, *models.APIKey) {
+	rawKey, prefix, hash, err := models.NewAPIKeySecret()
+	if err != nil {
+		t.Fatalf("generating API key: %v", err)
+	}
+
+	key := &models.APIKey{Name: "warehouse", Prefix: prefix, KeyHash: hash, Scopes: scopes}
+	if modify != nil {
+		modify(key)
+	}
+	a.db.Create(key)
+	return rawKey, key
+}
+
+func (a *authTestApp) getWithAPIKey(t *testing.T, path, key string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, a.server.URL+path, nil)
+	req.Header.Set(auth.APIKeyHeader, key)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestAPIKeys(t *testing.T) {
+	app := setupAuthTest(t)
+	auth.SetAPIKeyScopes(map[string]string{"GET /api/catalog-admin": models.ScopeCatalogWrite})
+	t.Cleanup(func() { auth.SetAPIKeyScopes(nil) })
+
+	t.Run("Accepts a key holding the route's scope and records its use", func(t *testing.T) {
+		rawKey, key := app.createAPIKey(t, models.ScopeCatalogRead+" "+models.ScopeCatalogWrite, nil)
+
+		resp := app.getWithAPIKey(t, "/api/catalog-admin", rawKey)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		var stored models.APIKey
+		app.db.First(&stored, key.ID)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+
+	t.Run("Rejects a key without the route's scope", func(t *testing.T) {
+		rawKey, _ := app.createAPIKey(t, models.ScopeCatalogRead, nil)
+
+		resp := app.getWithAPIKey(t, "/api/catalog-admin", rawKey)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Keeps keys off routes without a declared scope", func(t *testing.T) {
+		rawKey, _ := app.createAPIKey(t, models.ScopeCatalogWrite, nil)
+
+		resp := app.getWithAPIKey(t, "/api/whoami", rawKey)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Rejects unknown, tampered, revoked and expired keys", func(t *testing.T) {
+		valid, _ := app.createAPIKey(t, models.ScopeCatalogWrite, nil)
+		revoked, _ := app.createAPIKey(t, models.ScopeCatalogWrite, func(k *models.APIKey) {
+			now := time.Now()
+			k.RevokedAt = &now
+		})
+		expired, _ := app.createAPIKey(t, models.ScopeCatalogWrite, func(k *models.APIKey) {
+			past := time.Now().Add(-time.Minute)
+			k.ExpiresAt = &past
+		})
+		unknown, _, _, _ := models.NewAPIKeySecret()
+
+		for name, key := range map[string]string{
+			"unknown":  unknown,
+			"tampered": valid[:len(valid)-2] + "xx",
+			"revoked":  revoked,
+			"expired":  expired,
+			"garbage":  "not-a-key",
+		} {
+			resp := app.getWithAPIKey(t, "/api/catalog-admin", key)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+		}
+	})
+}
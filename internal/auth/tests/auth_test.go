@@ -0,0 +1,294 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// authTestApp is the API wired up against a fake identity provider, with
+// a browser-like client that keeps cookies and does not follow redirects.
+type authTestApp struct {
+	provider *fakeProvider
+	server   *httptest.Server
+	client   *http.Client
+	db       *gorm.DB
+}
+
+func setupAuthTest(t *testing.T) *authTestApp {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.Customer{}, &models.CustomerIdentity{}, &models.User{}, &models.Session{}, &models.APIKey{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	provider := newFakeProvider(t)
+
+	t.Setenv("OIDC_ISSUER", provider.issuer())
+	t.Setenv("OIDC_CLIENT_ID", testClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "test-secret")
+	t.Setenv("OIDC_REDIRECT_URL", "http://app.test/auth/callback")
+	t.Setenv("AUTH_RETURN_TO_ALLOWLIST", "/account, https://shop.example.com")
+	auth.Init()
+
+	r := gin.New()
+	r.Use(sessions.Sessions("gosess", cookie.NewStore([]byte("test-session-secret"))))
+	r.GET("/auth/providers", auth.ListProviders)
+	r.GET("/auth/login", auth.Login)
+	r.GET("/auth/login/:provider", auth.Login)
+	r.GET("/auth/callback", auth.Callback)
+	r.GET("/auth/callback/:provider", auth.Callback)
+	r.POST("/auth/logout", auth.Logout)
+	r.GET("/api/whoami", auth.RequireAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"customer": c.Value("customer"), "user": c.Value("user")})
+	})
+	r.GET("/api/catalog-admin", auth.RequireAuth(), auth.RequireRole(models.RoleCatalogManager), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &authTestApp{provider: provider, server: server, client: client, db: testDB}
+}
+
+func (a *authTestApp) post(t *testing.T, path string) *http.Response {
+	resp, err := a.client.Post(a.server.URL+path, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// signIn runs a whole login as the given user.
+func (a *authTestApp) signIn(t *testing.T, claims map[string]interface{}) {
+	code, state := a.provider.authorize(t, a.startLogin(t, ""), claims)
+	if resp := a.callback(t, code, state); resp.StatusCode != http.StatusOK {
+		t.Fatalf("login failed with %d", resp.StatusCode)
+	}
+}
+
+func (a *authTestApp) get(t *testing.T, path string) *http.Response {
+	resp, err := a.client.Get(a.server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// startLogin calls /auth/login and returns where it sent the browser.
+func (a *authTestApp) startLogin(t *testing.T, query string) string {
+	resp := a.get(t, "/auth/login"+query)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected login redirect, got %d", resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
+
+func (a *authTestApp) callback(t *testing.T, code, state string) *http.Response {
+	return a.get(t, "/auth/callback?"+url.Values{"code": {code}, "state": {state}}.Encode())
+}
+
+var janeClaims = map[string]interface{}{"sub": "jane-sub", "name": "Jane", "email": "jane@example.com"}
+
+func TestLogin(t *testing.T) {
+	app := setupAuthTest(t)
+
+	t.Run("Redirects with random state, nonce and an S256 challenge", func(t *testing.T) {
+		first, _ := url.Parse(app.startLogin(t, ""))
+		second, _ := url.Parse(app.startLogin(t, ""))
+
+		assert.True(t, strings.HasPrefix(first.String(), app.provider.issuer()+"/authorize"))
+		for _, param := range []string{"state", "nonce", "code_challenge"} {
+			assert.GreaterOrEqual(t, len(first.Query().Get(param)), 43, param)
+			assert.NotEqual(t, first.Query().Get(param), second.Query().Get(param), param)
+		}
+		assert.Equal(t, "S256", first.Query().Get("code_challenge_method"))
+	})
+
+	t.Run("Signs the user in and returns them where they started", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, "?return_to=/account/orders"), janeClaims)
+
+		resp := app.callback(t, code, state)
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, "/account/orders", resp.Header.Get("Location"))
+
+		assert.Equal(t, http.StatusOK, app.get(t, "/api/whoami").StatusCode)
+
+		var identity models.CustomerIdentity
+		assert.NoError(t, app.db.Where("issuer = ? AND subject = ?", app.provider.issuer(), "jane-sub").First(&identity).Error)
+		assert.Equal(t, config.DefaultOIDCProvider, identity.Provider)
+
+		var customer models.Customer
+		assert.NoError(t, app.db.First(&customer, identity.CustomerID).Error)
+		assert.Equal(t, "jane@example.com", customer.Email)
+	})
+
+	t.Run("Signs in several customers without an email", func(t *testing.T) {
+		for _, sub := range []string{"first-no-email", "second-no-email"} {
+			app.post(t, "/auth/logout")
+			app.signIn(t, map[string]interface{}{"sub": sub, "name": sub})
+		}
+
+		var customers int64
+		app.db.Model(&models.Customer{}).Where("email = ''").Count(&customers)
+		assert.Equal(t, int64(2), customers)
+	})
+
+	t.Run("Responds with JSON when there is no return_to", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims)
+
+		assert.Equal(t, http.StatusOK, app.callback(t, code, state).StatusCode)
+	})
+
+	t.Run("Allows absolute URLs on allowed origins", func(t *testing.T) {
+		target := "https://shop.example.com/cart?step=2"
+		code, state := app.provider.authorize(t, app.startLogin(t, "?return_to="+url.QueryEscape(target)), janeClaims)
+
+		resp := app.callback(t, code, state)
+		assert.Equal(t, target, resp.Header.Get("Location"))
+	})
+
+	t.Run("Rejects return_to outside the allow-list", func(t *testing.T) {
+		for _, target := range []string{
+			"/admin",
+			"/account/../admin",
+			"/accounts",
+			"//evil.example.com/account",
+			"/\\evil.example.com",
+			"https://evil.example.com/account",
+			"https://shop.example.com@evil.example.com/",
+			"http://shop.example.com/",
+			"javascript:alert(1)",
+		} {
+			resp := app.get(t, "/auth/login?return_to="+url.QueryEscape(target))
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, target)
+		}
+	})
+}
+
+func TestCallback(t *testing.T) {
+	app := setupAuthTest(t)
+
+	t.Run("Rejects a state that does not match the session", func(t *testing.T) {
+		code, _ := app.provider.authorize(t, app.startLogin(t, ""), janeClaims)
+
+		assert.Equal(t, http.StatusBadRequest, app.callback(t, code, "forged-state").StatusCode)
+	})
+
+	t.Run("Rejects a callback without a login in progress", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, app.callback(t, "some-code", "some-state").StatusCode)
+	})
+
+	t.Run("Accepts each state only once", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims)
+		assert.Equal(t, http.StatusOK, app.callback(t, code, state).StatusCode)
+
+		assert.Equal(t, http.StatusBadRequest, app.callback(t, code, state).StatusCode)
+	})
+
+	t.Run("Rejects an ID token with another nonce", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims, func(a *fakeAuthorization) {
+			a.Claims["nonce"] = "replayed-nonce"
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, app.callback(t, code, state).StatusCode)
+	})
+
+	t.Run("Sends the PKCE verifier with the code exchange", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims, func(a *fakeAuthorization) {
+			a.Challenge = "challenge-for-another-verifier"
+		})
+
+		assert.Equal(t, http.StatusBadRequest, app.callback(t, code, state).StatusCode)
+	})
+
+	t.Run("Reports errors from the provider", func(t *testing.T) {
+		app.startLogin(t, "")
+
+		resp := app.get(t, "/auth/callback?error=access_denied")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Rejects logins that took too long", func(t *testing.T) {
+		t.Setenv("AUTH_LOGIN_TIMEOUT", "1ns")
+		auth.Init()
+
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims)
+
+		assert.Equal(t, http.StatusBadRequest, app.callback(t, code, state).StatusCode)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	app := setupAuthTest(t)
+
+	t.Run("Ends the session", func(t *testing.T) {
+		app.signIn(t, janeClaims)
+		assert.Equal(t, http.StatusOK, app.get(t, "/api/whoami").StatusCode)
+
+		resp := app.post(t, "/auth/logout")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, app.get(t, "/api/whoami").StatusCode)
+	})
+
+	t.Run("Succeeds without a session", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, app.post(t, "/auth/logout").StatusCode)
+	})
+
+	t.Run("Redirects to the provider's end_session_endpoint", func(t *testing.T) {
+		app.provider.endSession = true
+		t.Setenv("OIDC_POST_LOGOUT_REDIRECT_URL", "https://shop.example.com/goodbye")
+		auth.Init()
+		t.Cleanup(func() {
+			app.provider.endSession = false
+			auth.Init()
+		})
+
+		app.signIn(t, janeClaims)
+
+		resp := app.post(t, "/auth/logout")
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+		location, _ := url.Parse(resp.Header.Get("Location"))
+		assert.Equal(t, app.provider.issuer()+"/logout", location.Scheme+"://"+location.Host+location.Path)
+		assert.Equal(t, testClientID, location.Query().Get("client_id"))
+		assert.Equal(t, "https://shop.example.com/goodbye", location.Query().Get("post_logout_redirect_uri"))
+
+		assert.Equal(t, http.StatusUnauthorized, app.get(t, "/api/whoami").StatusCode)
+	})
+}
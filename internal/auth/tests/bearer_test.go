@@ -0,0 +1,113 @@
+package auth_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+const testAPIAudience = "https://api.shop.example.com"
+
+// accessToken returns a signed access token for sub, valid for the API
+// unless overrides say otherwise.
+func (p *fakeProvider) accessToken(sub string, overrides map[string]interface{}) string {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": p.issuer(),
+		"aud": []string{testAPIAudience},
+		"sub": sub,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	return p.sign(claims)
+}
+
+func (a *authTestApp) getWithAuthorization(t *testing.T, path, authorization string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, a.server.URL+path, nil)
+	req.Header.Set("Authorization", authorization)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestBearerTokens(t *testing.T) {
+	app := setupAuthTest(t)
+	t.Setenv("AUTH_API_AUDIENCE", testAPIAudience)
+	auth.Init()
+
+	customer := models.Customer{Name: "Mobile", Email: "mobile@example.com"}
+	app.db.Create(&customer)
+	app.db.Create(&models.CustomerIdentity{CustomerID: customer.ID, Provider: "default", Issuer: app.provider.issuer(), Subject: "mobile-sub"})
+	issuer, staffSubject := app.provider.issuer(), "staff-sub"
+	app.db.Create(&models.User{Name: "Maya", Email: "maya@example.com", Role: models.RoleCatalogManager, Issuer: &issuer, Subject: &staffSubject})
+
+	t.Run("Resolves the subject to a customer", func(t *testing.T) {
+		resp := app.getWithAuthorization(t, "/api/whoami", "Bearer "+app.provider.accessToken("mobile-sub", nil))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = app.getWithAuthorization(t, "/api/catalog-admin", "Bearer "+app.provider.accessToken("mobile-sub", nil))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Resolves the subject to a staff user", func(t *testing.T) {
+		resp := app.getWithAuthorization(t, "/api/catalog-admin", "bearer "+app.provider.accessToken("staff-sub", nil))
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("Rejects tokens that fail verification", func(t *testing.T) {
+		other := newFakeProvider(t)
+
+		for name, token := range map[string]string{
+			"wrong audience":  app.provider.accessToken("mobile-sub", map[string]interface{}{"aud": testClientID + "-other"}),
+			"wrong issuer":    app.provider.accessToken("mobile-sub", map[string]interface{}{"iss": "https://evil.example.com"}),
+			"expired":         app.provider.accessToken("mobile-sub", map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}),
+			"foreign key":     other.accessToken("mobile-sub", map[string]interface{}{"iss": app.provider.issuer()}),
+			"ID token":        app.provider.accessToken("mobile-sub", map[string]interface{}{"nonce": "login-nonce"}),
+			"unknown subject": app.provider.accessToken("nobody", nil),
+			"garbage":         "not-a-jwt",
+		} {
+			resp := app.getWithAuthorization(t, "/api/whoami", "Bearer "+token)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+			assert.True(t, strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer "), name)
+		}
+	})
+
+	t.Run("Rejects other authorization schemes", func(t *testing.T) {
+		resp := app.getWithAuthorization(t, "/api/whoami", "Basic dXNlcjpwYXNz")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Works alongside cookie sessions", func(t *testing.T) {
+		app.signIn(t, janeClaims)
+		assert.Equal(t, http.StatusOK, app.get(t, "/api/whoami").StatusCode)
+
+		// A bad token is not rescued by a good cookie.
+		resp := app.getWithAuthorization(t, "/api/whoami", "Bearer not-a-jwt")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestBearerTokensNeedAnAPIAudience(t *testing.T) {
+	app := setupAuthTest(t)
+
+	customer := models.Customer{Name: "Mobile", Email: "mobile@example.com"}
+	app.db.Create(&customer)
+	app.db.Create(&models.CustomerIdentity{CustomerID: customer.ID, Provider: "default", Issuer: app.provider.issuer(), Subject: "mobile-sub"})
+
+	for name, aud := range map[string]interface{}{"API audience": []string{testAPIAudience}, "client ID": testClientID} {
+		resp := app.getWithAuthorization(t, "/api/whoami", "Bearer "+app.provider.accessToken("mobile-sub", map[string]interface{}{"aud": aud}))
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+	}
+}
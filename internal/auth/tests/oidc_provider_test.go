@@ -0,0 +1,187 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "test-client"
+	testKeyID    = "test-key"
+)
+
+// fakeProvider is a minimal OpenID Connect provider: discovery, JWKS and a
+// token endpoint that checks PKCE. Tests play the browser's part at the
+// authorization endpoint by calling authorize directly.
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// endSession makes discovery advertise an end_session_endpoint.
+	endSession bool
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthorization
+}
+
+// fakeAuthorization is what the provider remembers about an issued code.
+type fakeAuthorization struct {
+	Challenge string
+	Claims    map[string]interface{}
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	p := &fakeProvider{key: key, codes: make(map[string]fakeAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *fakeProvider) issuer() string {
+	return p.server.URL
+}
+
+func (p *fakeProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	doc := map[string]interface{}{
+		"issuer":                                p.issuer(),
+		"authorization_endpoint":                p.issuer() + "/authorize",
+		"token_endpoint":                        p.issuer() + "/token",
+		"jwks_uri":                              p.issuer() + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	}
+	if p.endSession {
+		doc["end_session_endpoint"] = p.issuer() + "/logout"
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (p *fakeProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize stands in for the user signing in at the provider. It reads
+// the request Login redirected to, issues a code for the given claims and
+// returns the code and state the provider would send back. modify, if
+// given, can tamper with what the provider remembers.
+func (p *fakeProvider) authorize(t *testing.T, location string, claims map[string]interface{}, modify ...func(*fakeAuthorization)) (string, string) {
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("bad authorization URL %q: %v", location, err)
+	}
+	q := u.Query()
+
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 code challenge, got %q", q.Get("code_challenge_method"))
+	}
+
+	auth := fakeAuthorization{Challenge: q.Get("code_challenge"), Claims: map[string]interface{}{"nonce": q.Get("nonce")}}
+	for k, v := range claims {
+		auth.Claims[k] = v
+	}
+	for _, fn := range modify {
+		fn(&auth)
+	}
+
+	code := "code-" + q.Get("state")
+
+	p.mu.Lock()
+	p.codes[code] = auth
+	p.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.Challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": p.issuer(),
+		"aud": testClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range auth.Claims {
+		claims[k] = v
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + r.PostForm.Get("code"),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(claims),
+	})
+}
+
+// sign returns claims as an RS256 JWT signed with the provider's key.
+func (p *fakeProvider) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": testKeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
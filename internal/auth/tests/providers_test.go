@@ -0,0 +1,223 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// setupProviders configures app.provider as "main" and a second fake
+// provider as "partner", which may not map roles or be trusted with
+// emails, and returns the second.
+func setupProviders(t *testing.T, app *authTestApp) *fakeProvider {
+	partner := newFakeProvider(t)
+
+	t.Setenv("AUTH_PROVIDERS", "main, partner")
+	for name, issuer := range map[string]string{"main": app.provider.issuer(), "partner": partner.issuer()} {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		t.Setenv(prefix+"ISSUER", issuer)
+		t.Setenv(prefix+"CLIENT_ID", testClientID)
+		t.Setenv(prefix+"CLIENT_SECRET", "test-secret")
+		t.Setenv(prefix+"REDIRECT_URL", "http://app.test/auth/callback/"+name)
+	}
+	t.Setenv("OIDC_MAIN_MAP_ROLES", "true")
+	t.Setenv("OIDC_MAIN_TRUST_EMAIL", "true")
+	t.Setenv("AUTH_API_AUDIENCE", testAPIAudience)
+	t.Setenv("AUTH_ROLE_MAPPINGS", "shop-admins=admin")
+	auth.Init()
+
+	return partner
+}
+
+// signInWith runs a whole login at the named provider and returns the
+// callback's status.
+func (a *authTestApp) signInWith(t *testing.T, name string, p *fakeProvider, claims map[string]interface{}) int {
+	resp := a.get(t, "/auth/login/"+name)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected login redirect, got %d", resp.StatusCode)
+	}
+
+	code, state := p.authorize(t, resp.Header.Get("Location"), claims)
+	return a.get(t, "/auth/callback/"+name+"?"+url.Values{"code": {code}, "state": {state}}.Encode()).StatusCode
+}
+
+func (a *authTestApp) bearerCustomer(t *testing.T, token string) *models.Customer {
+	req, _ := http.NewRequest(http.MethodGet, a.server.URL+"/api/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/whoami: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Customer *models.Customer `json:"customer"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body.Customer
+}
+
+func TestMultipleProviders(t *testing.T) {
+	app := setupAuthTest(t)
+	partner := setupProviders(t, app)
+
+	var jane *models.Customer
+
+	t.Run("Lists the providers", func(t *testing.T) {
+		resp, err := app.client.Get(app.server.URL + "/auth/providers")
+		if err != nil {
+			t.Fatalf("GET /auth/providers: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			Providers []string `json:"providers"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Equal(t, []string{"main", "partner"}, body.Providers)
+	})
+
+	t.Run("Sends each login to its provider", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(app.startLogin(t, ""), app.provider.issuer()+"/authorize"), "the first provider is the default")
+
+		resp := app.get(t, "/auth/login/partner")
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), partner.issuer()+"/authorize"))
+
+		assert.Equal(t, http.StatusNotFound, app.get(t, "/auth/login/nope").StatusCode)
+	})
+
+	t.Run("Rejects a response on another provider's callback", func(t *testing.T) {
+		code, state := app.provider.authorize(t, app.startLogin(t, ""), janeClaims)
+
+		resp := app.get(t, "/auth/callback/partner?"+url.Values{"code": {code}, "state": {state}}.Encode())
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Keeps the same subject at different issuers apart", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, janeClaims))
+		jane, _ = app.whoami(t)
+		app.post(t, "/auth/logout")
+
+		mallory := map[string]interface{}{"sub": "jane-sub", "name": "Mallory", "email": "mallory@example.com"}
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "partner", partner, mallory))
+		other, _ := app.whoami(t)
+		app.post(t, "/auth/logout")
+
+		if assert.NotNil(t, jane) && assert.NotNil(t, other) {
+			assert.NotEqual(t, jane.ID, other.ID)
+		}
+
+		fromPartner := app.bearerCustomer(t, partner.accessToken("jane-sub", nil))
+		if assert.NotNil(t, fromPartner) {
+			assert.Equal(t, other.ID, fromPartner.ID)
+		}
+		fromMain := app.bearerCustomer(t, app.provider.accessToken("jane-sub", nil))
+		if assert.NotNil(t, fromMain) {
+			assert.Equal(t, jane.ID, fromMain.ID)
+		}
+	})
+
+	t.Run("Links another provider to the signed-in customer", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, janeClaims))
+		second := map[string]interface{}{"sub": "jane-at-partner", "email": "jane.work@example.com"}
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "partner", partner, second))
+
+		customer, _ := app.whoami(t)
+		if assert.NotNil(t, customer) {
+			assert.Equal(t, jane.ID, customer.ID)
+		}
+
+		var providers []string
+		app.db.Model(&models.CustomerIdentity{}).Where("customer_id = ?", jane.ID).Order("id").Pluck("provider", &providers)
+		assert.Equal(t, []string{"main", "partner"}, providers)
+		app.post(t, "/auth/logout")
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "partner", partner, second))
+		customer, _ = app.whoami(t)
+		if assert.NotNil(t, customer) {
+			assert.Equal(t, jane.ID, customer.ID)
+		}
+		app.post(t, "/auth/logout")
+	})
+
+	t.Run("Links by email only at trusted providers that verified it", func(t *testing.T) {
+		claims := map[string]interface{}{"sub": "jane-new", "email": "jane@example.com", "email_verified": true}
+		assert.Equal(t, http.StatusConflict, app.signInWith(t, "partner", partner, claims))
+
+		claims = map[string]interface{}{"sub": "jane-again", "email": "Jane@Example.com"}
+		assert.Equal(t, http.StatusConflict, app.signInWith(t, "main", app.provider, claims))
+
+		claims["email_verified"] = true
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, claims))
+		customer, _ := app.whoami(t)
+		if assert.NotNil(t, customer) {
+			assert.Equal(t, jane.ID, customer.ID)
+		}
+		app.post(t, "/auth/logout")
+	})
+
+	t.Run("Maps roles only for providers trusted to", func(t *testing.T) {
+		admin := withGroups(staffClaims("boss-sub", "boss@example.com", true), "groups", []string{"shop-admins"})
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "partner", partner, admin))
+		_, user := app.whoami(t)
+		assert.Nil(t, user)
+		app.post(t, "/auth/logout")
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, admin))
+		_, user = app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleAdmin, user.Role)
+		}
+	})
+
+	t.Run("Binds staff by email only at providers trusted with roles", func(t *testing.T) {
+		carol := models.User{Name: "Carol", Email: "carol@example.com", Role: models.RoleSupport}
+		app.db.Create(&carol)
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "partner", partner, staffClaims("carol-partner", "carol@example.com", true)))
+		_, user := app.whoami(t)
+		assert.Nil(t, user)
+		app.post(t, "/auth/logout")
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, staffClaims("carol-sub", "carol@example.com", true)))
+		_, user = app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, carol.ID, user.ID)
+		}
+		app.post(t, "/auth/logout")
+
+		app.db.First(&carol, carol.ID)
+		if assert.NotNil(t, carol.Subject) && assert.NotNil(t, carol.Issuer) {
+			assert.Equal(t, "carol-sub", *carol.Subject)
+			assert.Equal(t, app.provider.issuer(), *carol.Issuer)
+		}
+	})
+
+	t.Run("Never rebinds a bound staff user", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, staffClaims("carol-impostor", "carol@example.com", true)))
+		_, user := app.whoami(t)
+		assert.Nil(t, user)
+		app.post(t, "/auth/logout")
+
+		var carol models.User
+		app.db.Where("email = ?", "carol@example.com").First(&carol)
+		if assert.NotNil(t, carol.Subject) {
+			assert.Equal(t, "carol-sub", *carol.Subject)
+		}
+
+		assert.Equal(t, http.StatusOK, app.signInWith(t, "main", app.provider, staffClaims("carol-sub", "carol.new@example.com", false)))
+		_, user = app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, carol.ID, user.ID, "the bound identity still signs in")
+		}
+		app.post(t, "/auth/logout")
+	})
+}
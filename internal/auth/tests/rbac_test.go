@@ -0,0 +1,89 @@
+package auth_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func staffClaims(sub, email string, verified interface{}) map[string]interface{} {
+	return map[string]interface{}{"sub": sub, "name": sub, "email": email, "email_verified": verified}
+}
+
+func TestRequireRole(t *testing.T) {
+	app := setupAuthTest(t)
+
+	manager := models.User{Name: "Maya", Email: "maya@example.com", Role: models.RoleCatalogManager}
+	app.db.Create(&manager)
+	app.db.Create(&models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin})
+	app.db.Create(&models.User{Name: "Sam", Email: "sam@example.com", Role: models.RoleSupport})
+
+	t.Run("Forbids customers without a staff role", func(t *testing.T) {
+		app.signIn(t, janeClaims)
+
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Allows staff with the role", func(t *testing.T) {
+		app.signIn(t, staffClaims("maya-sub", "Maya@Example.com", true))
+
+		assert.Equal(t, http.StatusNoContent, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Accepts email_verified sent as a string", func(t *testing.T) {
+		app.signIn(t, staffClaims("maya-sub", "maya@example.com", "true"))
+
+		assert.Equal(t, http.StatusNoContent, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Allows admins everywhere", func(t *testing.T) {
+		app.signIn(t, staffClaims("ada-sub", "ada@example.com", true))
+
+		assert.Equal(t, http.StatusNoContent, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Forbids staff with another role", func(t *testing.T) {
+		app.signIn(t, staffClaims("sam-sub", "sam@example.com", true))
+
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Ignores unverified emails", func(t *testing.T) {
+		app.signIn(t, staffClaims("maya-impostor", "maya@example.com", false))
+
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+	})
+
+	t.Run("Applies role changes on the next request", func(t *testing.T) {
+		app.signIn(t, staffClaims("maya-sub", "maya@example.com", true))
+		assert.Equal(t, http.StatusNoContent, app.get(t, "/api/catalog-admin").StatusCode)
+
+		app.db.Model(&manager).Update("role", models.RoleSupport)
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+
+		app.db.Delete(&manager)
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+		assert.Equal(t, http.StatusOK, app.get(t, "/api/whoami").StatusCode)
+	})
+}
+
+func TestSeedAdmins(t *testing.T) {
+	app := setupAuthTest(t)
+
+	app.db.Create(&models.User{Name: "Sam", Email: "sam@example.com", Role: models.RoleSupport})
+
+	assert.NoError(t, auth.SeedAdmins([]string{"sam@example.com", " New.Admin@example.com ", ""}))
+	assert.NoError(t, auth.SeedAdmins([]string{"new.admin@example.com"}))
+
+	var users []models.User
+	app.db.Order("email").Find(&users)
+	assert.Len(t, users, 2)
+	for _, user := range users {
+		assert.Equal(t, models.RoleAdmin, user.Role, user.Email)
+	}
+	assert.Equal(t, "new.admin@example.com", users[0].Email)
+}
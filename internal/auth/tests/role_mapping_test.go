@@ -0,0 +1,219 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/auth"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// whoami returns who the API thinks the browser is signed in as.
+func (a *authTestApp) whoami(t *testing.T) (*models.Customer, *models.User) {
+	resp, err := a.client.Get(a.server.URL + "/api/whoami")
+	if err != nil {
+		t.Fatalf("GET /api/whoami: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+
+	var body struct {
+		Customer *models.Customer `json:"customer"`
+		User     *models.User     `json:"user"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body.Customer, body.User
+}
+
+func withGroups(claims map[string]interface{}, key string, groups interface{}) map[string]interface{} {
+	merged := map[string]interface{}{key: groups}
+	for k, v := range claims {
+		merged[k] = v
+	}
+	return merged
+}
+
+func TestRoleMapping(t *testing.T) {
+	app := setupAuthTest(t)
+	t.Setenv("AUTH_ROLE_MAPPINGS", "shop-admins=admin, catalog-team=catalog-manager, warehouse=fulfilment")
+	auth.Init()
+
+	maya := staffClaims("maya-sub", "maya@example.com", true)
+
+	t.Run("Signs mapped users in as staff only", func(t *testing.T) {
+		app.signIn(t, withGroups(maya, "groups", []string{"everyone", "catalog-team"}))
+
+		customer, user := app.whoami(t)
+		assert.Nil(t, customer)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleCatalogManager, user.Role)
+			assert.Equal(t, models.RoleSourceIdP, user.RoleSource)
+		}
+		assert.Equal(t, http.StatusNoContent, app.get(t, "/api/catalog-admin").StatusCode)
+
+		var identities int64
+		app.db.Model(&models.CustomerIdentity{}).Where("subject = ?", "maya-sub").Count(&identities)
+		assert.Equal(t, int64(0), identities)
+	})
+
+	t.Run("Picks the most privileged mapped role", func(t *testing.T) {
+		app.signIn(t, withGroups(maya, "groups", []string{"warehouse", "shop-admins"}))
+
+		_, user := app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleAdmin, user.Role)
+		}
+	})
+
+	t.Run("Follows role changes in the IdP on every login", func(t *testing.T) {
+		app.signIn(t, withGroups(maya, "groups", []string{"warehouse"}))
+
+		_, user := app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleFulfilment, user.Role)
+		}
+		assert.Equal(t, http.StatusForbidden, app.get(t, "/api/catalog-admin").StatusCode)
+
+		var count int64
+		app.db.Model(&models.User{}).Where("email = ?", "maya@example.com").Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Removes staff the IdP no longer maps", func(t *testing.T) {
+		app.signIn(t, withGroups(maya, "groups", []string{"everyone"}))
+
+		customer, user := app.whoami(t)
+		assert.Nil(t, user)
+		assert.NotNil(t, customer)
+
+		var count int64
+		app.db.Model(&models.User{}).Where("email = ?", "maya@example.com").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Leaves manual grants alone", func(t *testing.T) {
+		app.db.Create(&models.User{Name: "Sam", Email: "sam@example.com", Role: models.RoleSupport})
+
+		app.signIn(t, withGroups(staffClaims("sam-sub", "sam@example.com", true), "groups", []string{"everyone"}))
+
+		_, user := app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleSupport, user.Role)
+			assert.Equal(t, models.RoleSourceManual, user.RoleSource)
+		}
+	})
+
+	t.Run("Keeps a role an admin set over later mapped logins", func(t *testing.T) {
+		lee := staffClaims("lee-sub", "lee@example.com", true)
+		app.signIn(t, withGroups(lee, "groups", []string{"warehouse"}))
+
+		var staff models.User
+		app.db.Where("email = ?", "lee@example.com").First(&staff)
+		assert.Equal(t, models.RoleSourceIdP, staff.RoleSource)
+
+		admin := &models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin}
+		app.db.Create(admin)
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("user", admin)
+			c.Next()
+		})
+		r.PUT("/api/admin/users/:id/role", handlers.UpdateStaffRole)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", staff.ID), strings.NewReader(`{"role":"support"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		app.signIn(t, withGroups(lee, "groups", []string{"shop-admins"}))
+		_, user := app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleSupport, user.Role)
+			assert.Equal(t, models.RoleSourceManual, user.RoleSource)
+		}
+
+		app.signIn(t, withGroups(lee, "groups", []string{"everyone"}))
+		_, user = app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleSupport, user.Role)
+		}
+	})
+
+	t.Run("Never takes over staff bound to another identity", func(t *testing.T) {
+		app.signIn(t, withGroups(staffClaims("noa-sub", "noa@example.com", true), "groups", []string{"warehouse"}))
+
+		other := staffClaims("other-sub", "noa@example.com", true)
+		app.signIn(t, withGroups(other, "groups", []string{"shop-admins"}))
+		_, user := app.whoami(t)
+		assert.Nil(t, user)
+
+		app.signIn(t, withGroups(other, "groups", []string{"everyone"}))
+
+		var noa models.User
+		if assert.NoError(t, app.db.Where("email = ?", "noa@example.com").First(&noa).Error) {
+			assert.Equal(t, models.RoleFulfilment, noa.Role)
+			assert.Equal(t, "noa-sub", *noa.Subject)
+		}
+	})
+
+	t.Run("Requires a verified email", func(t *testing.T) {
+		app.signIn(t, withGroups(staffClaims("eve-sub", "eve@example.com", false), "groups", []string{"shop-admins"}))
+
+		_, user := app.whoami(t)
+		assert.Nil(t, user)
+	})
+
+	t.Run("Reads nested claims and space-separated values", func(t *testing.T) {
+		t.Setenv("AUTH_ROLE_CLAIM", "realm_access.roles")
+		auth.Init()
+
+		app.signIn(t, withGroups(staffClaims("kim-sub", "kim@example.com", true), "realm_access", map[string]interface{}{"roles": []string{"warehouse"}}))
+		_, user := app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleFulfilment, user.Role)
+		}
+
+		t.Setenv("AUTH_ROLE_CLAIM", "roles")
+		auth.Init()
+
+		app.signIn(t, withGroups(staffClaims("kim-sub", "kim@example.com", true), "roles", "offline_access shop-admins"))
+		_, user = app.whoami(t)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, models.RoleAdmin, user.Role)
+		}
+	})
+}
+
+func TestRoleMappingKeepsLastAdmin(t *testing.T) {
+	app := setupAuthTest(t)
+	t.Setenv("AUTH_ROLE_MAPPINGS", "shop-admins=admin")
+	auth.Init()
+
+	ada := staffClaims("ada-sub", "ada@example.com", true)
+	app.signIn(t, withGroups(ada, "groups", []string{"shop-admins"}))
+	app.signIn(t, withGroups(ada, "groups", []string{"everyone"}))
+
+	_, user := app.whoami(t)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, models.RoleAdmin, user.Role)
+	}
+
+	app.db.Create(&models.User{Name: "Ben", Email: "ben@example.com", Role: models.RoleAdmin})
+	app.signIn(t, withGroups(ada, "groups", []string{"everyone"}))
+
+	var count int64
+	app.db.Model(&models.User{}).Where("email = ?", "ada@example.com").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
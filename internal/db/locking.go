@@ -0,0 +1,17 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ForUpdate adds SELECT ... FOR UPDATE row locking on databases that
+// support it. SQLite has no row locks and rejects the clause, but it
+// serialises writers, so the query is returned unchanged there.
+func ForUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialector.Name() == "sqlite" {
+		return tx
+	}
+
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
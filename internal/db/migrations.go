@@ -0,0 +1,264 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// dataMigration records a one-off data migration that has been applied, for
+// migrations whose effect cannot be detected from the schema alone.
+type dataMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+// Migrate brings the schema up to date. One-off migrations that AutoMigrate
+// cannot express run either side of it, each guarded so it is a no-op once
+// applied: schema rewrites before, data fixes after.
+func Migrate(database *gorm.DB) error {
+	if err := migrateFloatPricesToMoney(database); err != nil {
+		return err
+	}
+
+	if err := addProductStock(database); err != nil {
+		return err
+	}
+
+	if err := dropCustomerEmailIndex(database); err != nil {
+		return err
+	}
+
+	if err := autoMigrate(database); err != nil {
+		return err
+	}
+
+	if err := runOnce(database, "normalize_customer_phones", normalizeCustomerPhones); err != nil {
+		return err
+	}
+
+	return runOnce(database, "link_customer_identities", linkCustomerIdentities)
+}
+
+func autoMigrate(database *gorm.DB) error {
+	return database.AutoMigrate(
+		&dataMigration{},
+		&models.Category{},
+		&models.Product{},
+		&models.Customer{},
+		&models.CustomerIdentity{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.User{},
+		&models.InventoryMovement{},
+		&models.OrderStatusHistory{},
+		&models.IdempotencyKey{},
+		&models.OutboxMessage{},
+		&models.SMSDelivery{},
+		&models.NotificationPreference{},
+		&models.Session{},
+		&models.APIKey{},
+	)
+}
+
+// runOnce applies migrate in a transaction and records it under name, so
+// it is skipped on later runs.
+func runOnce(database *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	var applied int64
+	if err := database.Model(&dataMigration{}).Where("name = ?", name).Count(&applied).Error; err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&dataMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("data migration %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// normalizeCustomerPhones rewrites stored phone numbers into E.164 using
+// the configured default country. Numbers that cannot be normalized are
+// left untouched and logged so they can be fixed by hand.
+func normalizeCustomerPhones(tx *gorm.DB) error {
+	defaultCountry := config.LoadDefaultPhoneCountry()
+
+	var customers []models.Customer
+	if err := tx.Select("id", "phone").Where("phone <> ''").Find(&customers).Error; err != nil {
+		return err
+	}
+
+	for _, customer := range customers {
+		normalized, err := models.NormalizePhone(customer.Phone, defaultCountry)
+		if err != nil {
+			log.Printf("Leaving phone number of customer %d as is: %v", customer.ID, err)
+			continue
+		}
+		if normalized == customer.Phone {
+			continue
+		}
+
+		if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).Update("phone", normalized).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkCustomerIdentities moves the OIDC subjects stored on customers when
+// only one provider was supported into customer_identities, and records
+// the issuer of staff subjects. Both are attributed to the first
+// configured provider, which for an existing install is the one its
+// OIDC_ISSUER names.
+func linkCustomerIdentities(tx *gorm.DB) error {
+	provider := config.LoadOIDCProviders()[0]
+	migrator := tx.Migrator()
+
+	if migrator.HasColumn("customers", "o_id_c_id") {
+		var legacy int64
+		if err := tx.Table("customers").Where("o_id_c_id IS NOT NULL AND o_id_c_id <> ''").Count(&legacy).Error; err != nil {
+			return err
+		}
+		if legacy > 0 && provider.Issuer == "" {
+			return fmt.Errorf("the issuer of provider %q is needed to keep %d existing customer logins", provider.Name, legacy)
+		}
+
+		err := tx.Exec(
+			"INSERT INTO customer_identities (customer_id, provider, issuer, subject, email, created_at) "+
+				"SELECT id, ?, ?, o_id_c_id, email, ? FROM customers WHERE o_id_c_id IS NOT NULL AND o_id_c_id <> ''",
+			provider.Name, provider.Issuer, time.Now(),
+		).Error
+		if err != nil {
+			return err
+		}
+
+		if migrator.HasIndex("customers", "idx_customers_o_id_c_id") {
+			if err := migrator.DropIndex("customers", "idx_customers_o_id_c_id"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("ALTER TABLE customers DROP COLUMN o_id_c_id").Error; err != nil {
+			return err
+		}
+	}
+
+	if provider.Issuer != "" {
+		err := tx.Model(&models.User{}).Where("subject IS NOT NULL AND issuer IS NULL").Update("issuer", provider.Issuer).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// Subjects are now unique per issuer, not on their own.
+	if migrator.HasIndex(&models.User{}, "idx_users_subject") {
+		return migrator.DropIndex(&models.User{}, "idx_users_subject")
+	}
+	return nil
+}
+
+// dropCustomerEmailIndex drops the unique index that covered every
+// customer email, which let only one customer sign in without an email.
+// AutoMigrate replaces it with one that skips empty emails.
+func dropCustomerEmailIndex(database *gorm.DB) error {
+	migrator := database.Migrator()
+	if !migrator.HasTable("customers") || !migrator.HasIndex("customers", "idx_customers_email") {
+		return nil
+	}
+	return migrator.DropIndex("customers", "idx_customers_email")
+}
+
+// addProductStock adds the stock column to a products table created before
+// stock was tracked. Left at 0, every existing product would refuse orders,
+// so they are given PRODUCT_INITIAL_STOCK units, recorded in the ledger as
+// initial stock. Without it they stay at 0 and a warning says how many
+// products need restocking.
+func addProductStock(database *gorm.DB) error {
+	migrator := database.Migrator()
+	if !migrator.HasTable("products") || migrator.HasColumn("products", "stock") {
+		return nil
+	}
+
+	initial := config.LoadInitialStock()
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&models.Product{}, "Stock"); err != nil {
+			return err
+		}
+
+		var products int64
+		if err := tx.Table("products").Count(&products).Error; err != nil {
+			return err
+		}
+		if products == 0 {
+			return nil
+		}
+		if initial == 0 {
+			log.Printf("%d existing products have no stock and cannot be ordered until restocked; set PRODUCT_INITIAL_STOCK to give them stock when migrating", products)
+			return nil
+		}
+
+		if err := tx.AutoMigrate(&models.InventoryMovement{}); err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE products SET stock = ?", initial).Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			"INSERT INTO inventory_movements (product_id, change, balance_after, reason, note, created_at) "+
+				"SELECT id, ?, ?, ?, ?, ? FROM products",
+			initial, initial, models.InventoryReasonInitial, "stock tracking introduced", time.Now(),
+		).Error
+	})
+	if err != nil {
+		return fmt.Errorf("adding products.stock: %w", err)
+	}
+
+	return nil
+}
+
+// migrateFloatPricesToMoney converts the legacy float64 price columns into
+// integer minor units in DefaultCurrency, then drops the old column.
+func migrateFloatPricesToMoney(database *gorm.DB) error {
+	for _, table := range []string{"products", "order_items"} {
+		migrator := database.Migrator()
+		if !migrator.HasTable(table) || !migrator.HasColumn(table, "price") || migrator.HasColumn(table, "price_amount") {
+			continue
+		}
+
+		err := database.Transaction(func(tx *gorm.DB) error {
+			statements := []string{
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN price_amount bigint NOT NULL DEFAULT 0", table),
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN price_currency varchar(3) NOT NULL DEFAULT '%s'", table, models.DefaultCurrency),
+				fmt.Sprintf("UPDATE %s SET price_amount = ROUND(price * 100)", table),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN price", table),
+			}
+
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migrating %s.price to money: %w", table, err)
+		}
+	}
+
+	return nil
+}
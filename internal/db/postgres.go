@@ -7,8 +7,6 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-
-	"github.com/Keoroanthony/go-ecommerce/internal/models"
 )
 
 var DB *gorm.DB
@@ -33,14 +31,7 @@ func Init() {
 		log.Fatalf("Failed to connect to DB: %v", err)
 	}
 
-	err = DB.AutoMigrate(
-		&models.Category{},
-		&models.Product{},
-		&models.Customer{},
-		&models.Order{},
-		&models.OrderItem{},
-		&models.User{},
-	)
+	err = Migrate(DB)
 
 	if err != nil {
 
@@ -0,0 +1,210 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupMigrationTestDB(t *testing.T) *gorm.DB {
+	// A private in-memory database so the legacy schema below does not
+	// collide with tables created by other tests.
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	return testDB
+}
+
+func TestMigrateFloatPricesToMoney(t *testing.T) {
+	testDB := setupMigrationTestDB(t)
+
+	// Schema and rows as they were while prices were float64.
+	testDB.Exec("CREATE TABLE categories (id integer PRIMARY KEY, name text NOT NULL UNIQUE, parent_id integer)")
+	testDB.Exec("CREATE TABLE products (id integer PRIMARY KEY, name text NOT NULL, price real NOT NULL, category_id integer NOT NULL)")
+	testDB.Exec("CREATE TABLE order_items (id integer PRIMARY KEY, order_id integer NOT NULL, product_id integer NOT NULL, quantity integer NOT NULL, price real NOT NULL, created_at datetime)")
+	testDB.Exec("INSERT INTO categories (id, name) VALUES (1, 'Electronics')")
+	testDB.Exec("INSERT INTO products (id, name, price, category_id) VALUES (1, 'Laptop', 1199.99, 1), (2, 'Cable', 0.29, 1)")
+	testDB.Exec("INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (1, 1, 2, 1, 0.29)")
+
+	assert.NoError(t, db.Migrate(testDB))
+
+	var laptop, cable models.Product
+	testDB.First(&laptop, 1)
+	testDB.First(&cable, 2)
+	assert.Equal(t, models.NewMoney(119999, "KES"), laptop.Price)
+	assert.Equal(t, models.NewMoney(29, "KES"), cable.Price)
+
+	var item models.OrderItem
+	testDB.First(&item, 1)
+	assert.Equal(t, models.NewMoney(29, "KES"), item.Price)
+
+	assert.False(t, testDB.Migrator().HasColumn("products", "price"))
+	assert.False(t, testDB.Migrator().HasColumn("order_items", "price"))
+
+	t.Run("Is a no-op when run again", func(t *testing.T) {
+		assert.NoError(t, db.Migrate(testDB))
+
+		var again models.Product
+		testDB.First(&again, 1)
+		assert.Equal(t, models.NewMoney(119999, "KES"), again.Price)
+	})
+}
+
+func TestNormalizeCustomerPhones(t *testing.T) {
+	t.Setenv("PHONE_DEFAULT_COUNTRY", "KE")
+	testDB := setupMigrationTestDB(t)
+
+	assert.NoError(t, testDB.AutoMigrate(&models.Customer{}))
+	customers := []models.Customer{
+		{Name: "National", Email: "national@example.com", Phone: "0712 345 678"},
+		{Name: "Spaced", Email: "spaced@example.com", Phone: "+254 722 000 111"},
+		{Name: "Normalized", Email: "normalized@example.com", Phone: "+254733000222"},
+		{Name: "Broken", Email: "broken@example.com", Phone: "12"},
+		{Name: "Missing", Email: "missing@example.com", Phone: ""},
+	}
+	testDB.Create(&customers)
+
+	assert.NoError(t, db.Migrate(testDB))
+
+	phones := map[string]string{}
+	var reloaded []models.Customer
+	testDB.Find(&reloaded)
+	for _, customer := range reloaded {
+		phones[customer.Name] = customer.Phone
+	}
+
+	assert.Equal(t, "+254712345678", phones["National"])
+	assert.Equal(t, "+254722000111", phones["Spaced"])
+	assert.Equal(t, "+254733000222", phones["Normalized"])
+	assert.Equal(t, "12", phones["Broken"], "invalid numbers are left for manual repair")
+	assert.Equal(t, "", phones["Missing"])
+
+	t.Run("Runs only once", func(t *testing.T) {
+		late := models.Customer{Name: "Late", Email: "late@example.com", Phone: "0799 000 333"}
+		testDB.Create(&late)
+
+		assert.NoError(t, db.Migrate(testDB))
+
+		var again models.Customer
+		testDB.First(&again, late.ID)
+		assert.Equal(t, "0799 000 333", again.Phone)
+	})
+}
+
+func TestLinkCustomerIdentities(t *testing.T) {
+	t.Setenv("AUTH_PROVIDERS", "")
+	t.Setenv("OIDC_ISSUER", "https://login.example.com")
+	testDB := setupMigrationTestDB(t)
+
+	// Schema and rows as they were while customers had a single OIDC subject.
+	testDB.Exec("CREATE TABLE customers (id integer PRIMARY KEY, name text NOT NULL, email text NOT NULL, phone text NOT NULL, o_id_c_id text, locale text)")
+	testDB.Exec("CREATE UNIQUE INDEX idx_customers_o_id_c_id ON customers (o_id_c_id)")
+	testDB.Exec("CREATE TABLE users (id integer PRIMARY KEY, name text NOT NULL, email text NOT NULL, role text NOT NULL, role_source text NOT NULL DEFAULT 'manual', subject text, created_at datetime)")
+	testDB.Exec("CREATE UNIQUE INDEX idx_users_subject ON users (subject)")
+	testDB.Exec("INSERT INTO customers (id, name, email, phone, o_id_c_id) VALUES (1, 'Jane', 'jane@example.com', '', 'jane-sub'), (2, 'Guest', 'guest@example.com', '', NULL)")
+	testDB.Exec("INSERT INTO users (id, name, email, role, subject) VALUES (1, 'Maya', 'maya@example.com', 'support', 'maya-sub'), (2, 'Ada', 'ada@example.com', 'admin', NULL)")
+
+	assert.NoError(t, db.Migrate(testDB))
+
+	var identities []models.CustomerIdentity
+	testDB.Find(&identities)
+	if assert.Len(t, identities, 1) {
+		assert.Equal(t, uint(1), identities[0].CustomerID)
+		assert.Equal(t, "default", identities[0].Provider)
+		assert.Equal(t, "https://login.example.com", identities[0].Issuer)
+		assert.Equal(t, "jane-sub", identities[0].Subject)
+		assert.Equal(t, "jane@example.com", identities[0].Email)
+	}
+	assert.False(t, testDB.Migrator().HasColumn("customers", "o_id_c_id"))
+
+	var maya, ada models.User
+	testDB.First(&maya, 1)
+	testDB.First(&ada, 2)
+	if assert.NotNil(t, maya.Issuer) {
+		assert.Equal(t, "https://login.example.com", *maya.Issuer)
+	}
+	assert.Nil(t, ada.Issuer)
+	assert.False(t, testDB.Migrator().HasIndex(&models.User{}, "idx_users_subject"))
+
+	t.Run("Lets subjects repeat across issuers", func(t *testing.T) {
+		assert.NoError(t, testDB.Create(&models.CustomerIdentity{CustomerID: 2, Provider: "google", Issuer: "https://accounts.google.com", Subject: "jane-sub"}).Error)
+		assert.Error(t, testDB.Create(&models.CustomerIdentity{CustomerID: 2, Provider: "default", Issuer: "https://login.example.com", Subject: "jane-sub"}).Error)
+	})
+}
+
+func TestAddProductStock(t *testing.T) {
+	legacySchema := func(t *testing.T) *gorm.DB {
+		testDB := setupMigrationTestDB(t)
+
+		// Schema and rows as they were before stock was tracked.
+		testDB.Exec("CREATE TABLE categories (id integer PRIMARY KEY, name text NOT NULL UNIQUE, parent_id integer)")
+		testDB.Exec("CREATE TABLE products (id integer PRIMARY KEY, name text NOT NULL, price_amount bigint NOT NULL DEFAULT 0, price_currency varchar(3) NOT NULL DEFAULT 'KES', category_id integer NOT NULL)")
+		testDB.Exec("INSERT INTO categories (id, name) VALUES (1, 'Electronics')")
+		testDB.Exec("INSERT INTO products (id, name, price_amount, category_id) VALUES (1, 'Laptop', 119999, 1), (2, 'Cable', 29, 1)")
+		return testDB
+	}
+
+	t.Run("Gives existing products the configured initial stock", func(t *testing.T) {
+		t.Setenv("PRODUCT_INITIAL_STOCK", "25")
+		testDB := legacySchema(t)
+
+		assert.NoError(t, db.Migrate(testDB))
+
+		var products []models.Product
+		testDB.Order("id").Find(&products)
+		if assert.Len(t, products, 2) {
+			assert.Equal(t, 25, products[0].Stock)
+			assert.Equal(t, 25, products[1].Stock)
+		}
+
+		var movements []models.InventoryMovement
+		testDB.Order("product_id").Find(&movements)
+		if assert.Len(t, movements, 2) {
+			assert.Equal(t, 25, movements[0].Change)
+			assert.Equal(t, 25, movements[0].BalanceAfter)
+			assert.Equal(t, models.InventoryReasonInitial, movements[0].Reason)
+		}
+
+		assert.NoError(t, db.Migrate(testDB))
+		var count int64
+		testDB.Model(&models.InventoryMovement{}).Count(&count)
+		assert.Equal(t, int64(2), count, "runs only once")
+	})
+
+	t.Run("Leaves stock at zero without an initial stock", func(t *testing.T) {
+		t.Setenv("PRODUCT_INITIAL_STOCK", "")
+		testDB := legacySchema(t)
+
+		assert.NoError(t, db.Migrate(testDB))
+
+		var laptop models.Product
+		testDB.First(&laptop, 1)
+		assert.Equal(t, 0, laptop.Stock)
+
+		var count int64
+		testDB.Model(&models.InventoryMovement{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestDropCustomerEmailIndex(t *testing.T) {
+	testDB := setupMigrationTestDB(t)
+
+	// Schema as it was while every customer email had to be unique.
+	testDB.Exec("CREATE TABLE customers (id integer PRIMARY KEY, name text NOT NULL, email text NOT NULL, phone text NOT NULL, locale text)")
+	testDB.Exec("CREATE UNIQUE INDEX idx_customers_email ON customers (email)")
+	testDB.Exec("INSERT INTO customers (id, name, email, phone) VALUES (1, 'Jane', 'jane@example.com', ''), (2, 'Guest', '', '')")
+
+	assert.NoError(t, db.Migrate(testDB))
+
+	assert.False(t, testDB.Migrator().HasIndex("customers", "idx_customers_email"))
+	assert.NoError(t, testDB.Create(&models.Customer{Name: "Another guest"}).Error)
+	assert.Error(t, testDB.Create(&models.Customer{Name: "Jane again", Email: "jane@example.com"}).Error)
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RotateAPIKeyRequest sets how long the old key keeps working after a
+// rotation, as a Go duration such as "24h". Without it the old key stops
+// working straight away.
+type RotateAPIKeyRequest struct {
+	GracePeriod string `json:"grace_period"`
+}
+
+// APIKeyResponse is an API key as shown to admins. Key is only set in the
+// response to creating or rotating a key; it cannot be recovered later.
+type APIKeyResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	Active      bool       `json:"active"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID *uint      `json:"created_by_id"`
+	RotatedToID *uint      `json:"rotated_to_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Key         string     `json:"key,omitempty"`
+}
+
+// errAPIKeyInactive stops rotating a key that is revoked or expired.
+var errAPIKeyInactive = errors.New("API key is revoked or expired")
+
+func newAPIKeyResponse(key models.APIKey, now time.Time) APIKeyResponse {
+	return APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Scopes:      key.ScopeList(),
+		Active:      key.Active(now),
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+		RevokedAt:   key.RevokedAt,
+		CreatedByID: key.CreatedByID,
+		RotatedToID: key.RotatedToID,
+		CreatedAt:   key.CreatedAt,
+	}
+}
+
+// ListAPIKeys lists API keys, newest first. Revoked keys are left out
+// unless ?include_revoked=true.
+func ListAPIKeys(c *gin.Context) {
+	query := db.DB.Order("id DESC")
+	if c.Query("include_revoked") != "true" {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	var keys []models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key, now))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": response, "scopes": models.APIKeyScopes})
+}
+
+// CreateAPIKey issues a key for another system. The key is in the response
+// and is not stored, so it must be copied then.
+func CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	key := models.APIKey{Name: name, Scopes: scopes, ExpiresAt: req.ExpiresAt}
+	if user, ok := c.Get("user"); ok {
+		if user, ok := user.(*models.User); ok {
+			key.CreatedByID = &user.ID
+		}
+	}
+
+	rawKey, err := issueAPIKey(db.DB, &key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := newAPIKeyResponse(key, now)
+	response.Key = rawKey
+	c.JSON(http.StatusCreated, response)
+}
+
+// RevokeAPIKey stops a key working. Revoking a revoked key is a no-op.
+func RevokeAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var key models.APIKey
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).First(&key, id).Error; err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		key.RevokedAt = &now
+		return tx.Model(&key).Update("revoked_at", now).Error
+	})
+
+	if err != nil {
+		respondAPIKeyError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAPIKeyResponse(key, time.Now()))
+}
+
+// RotateAPIKey replaces a key with a new one holding the same name, scopes
+// and expiry. The old key keeps working for the requested grace period, so
+// the integration can switch over without downtime.
+func RotateAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		parsed, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grace_period must be a duration such as 24h"})
+			return
+		}
+		grace = parsed
+	}
+
+	now := time.Now()
+	var old, replacement models.APIKey
+	var rawKey string
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).First(&old, id).Error; err != nil {
+			return err
+		}
+		if !old.Active(now) {
+			return errAPIKeyInactive
+		}
+
+		replacement = models.APIKey{Name: old.Name, Scopes: old.Scopes, ExpiresAt: old.ExpiresAt, CreatedByID: old.CreatedByID}
+		if user, ok := c.Get("user"); ok {
+			if user, ok := user.(*models.User); ok {
+				replacement.CreatedByID = &user.ID
+			}
+		}
+
+		var err error
+		if rawKey, err = issueAPIKey(tx, &replacement); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"rotated_to_id": replacement.ID}
+		if grace == 0 {
+			updates["revoked_at"] = now
+		} else if cutoff := now.Add(grace); old.ExpiresAt == nil || cutoff.Before(*old.ExpiresAt) {
+			updates["expires_at"] = cutoff
+		}
+		if err := tx.Model(&old).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return tx.First(&old, old.ID).Error
+	})
+
+	if err != nil {
+		respondAPIKeyError(c, id, err)
+		return
+	}
+
+	response := newAPIKeyResponse(replacement, now)
+	response.Key = rawKey
+	c.JSON(http.StatusOK, gin.H{"api_key": response, "previous": newAPIKeyResponse(old, now)})
+}
+
+// issueAPIKey generates a secret for key, stores key and returns the
+// secret.
+func issueAPIKey(tx *gorm.DB, key *models.APIKey) (string, error) {
+	rawKey, prefix, hash, err := models.NewAPIKeySecret()
+	if err != nil {
+		return "", err
+	}
+
+	key.Prefix = prefix
+	key.KeyHash = hash
+	if err := tx.Create(key).Error; err != nil {
+		return "", err
+	}
+
+	return rawKey, nil
+}
+
+// normalizeAPIKeyScopes validates scopes and returns them sorted, without
+// duplicates, in the space-separated form APIKey stores.
+func normalizeAPIKeyScopes(scopes []string) (string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !models.IsValidAPIKeyScope(scope) {
+			return "", fmt.Errorf("unknown scope: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+
+	sort.Strings(normalized)
+	return strings.Join(normalized, " "), nil
+}
+
+func respondAPIKeyError(c *gin.Context, id uint, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("API key not found with ID: %d", id)})
+	case errors.Is(err, errAPIKeyInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
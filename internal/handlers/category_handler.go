@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/Keoroanthony/go-ecommerce/internal/db"
 	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+	"gorm.io/gorm"
 )
 
 type CreateCategoryRequest struct {
@@ -52,4 +56,377 @@ func CreateCategory(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, category)
-}
\ No newline at end of file
+}
+
+// OptionalParentID distinguishes an omitted parent_id from an explicit null
+// in PATCH bodies: Set is true whenever the key is present.
+type OptionalParentID struct {
+	Set   bool
+	Value *uint
+}
+
+func (o *OptionalParentID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var id uint
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	o.Value = &id
+	return nil
+}
+
+type UpdateCategoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type PatchCategoryRequest struct {
+	Name     *string          `json:"name" binding:"omitempty,min=1"`
+	ParentID OptionalParentID `json:"parent_id"`
+}
+
+// Strategies accepted by DeleteCategory via the ?strategy= query parameter.
+const (
+	DeleteStrategyReject   = "reject"
+	DeleteStrategyCascade  = "cascade"
+	DeleteStrategyReassign = "reassign"
+)
+
+func ListCategories(c *gin.Context) {
+	query := db.DB.Order("id")
+
+	if parentIDParam := c.Query("parent_id"); parentIDParam != "" {
+		if parentIDParam == "root" {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			var parentID uint
+			if _, err := fmt.Sscan(parentIDParam, &parentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent_id"})
+				return
+			}
+			query = query.Where("parent_id = ?", parentID)
+		}
+	}
+
+	var categories []models.Category
+	if err := query.Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+func GetCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var category models.Category
+	if err := db.DB.Preload("Parent").Preload("Children").First(&category, id).Error; err != nil {
+		respondCategoryLookupError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// GetCategoryTree returns the category hierarchy as nested JSON. An optional
+// root_id narrows it to one subtree and max_depth limits how many levels are
+// returned, counting the roots as level 1.
+func GetCategoryTree(c *gin.Context) {
+	maxDepth := 0
+	if maxDepthParam := c.Query("max_depth"); maxDepthParam != "" {
+		if _, err := fmt.Sscan(maxDepthParam, &maxDepth); err != nil || maxDepth < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_depth must be a positive integer"})
+			return
+		}
+	}
+
+	query := db.DB.Order("id")
+
+	if rootIDParam := c.Query("root_id"); rootIDParam != "" {
+		var rootID uint
+		if _, err := fmt.Sscan(rootIDParam, &rootID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid root_id"})
+			return
+		}
+
+		var root models.Category
+		if err := db.DB.First(&root, rootID).Error; err != nil {
+			respondCategoryLookupError(c, rootID, err)
+			return
+		}
+
+		categoryIDs, err := utils.GetAllCategoryIDs(rootID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("id IN ?", categoryIDs)
+	}
+
+	var categories []models.Category
+	if err := query.Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.BuildCategoryTree(categories, maxDepth))
+}
+
+// GetCategoryBreadcrumbs returns the path from the top-level root down to
+// the requested category.
+func GetCategoryBreadcrumbs(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	path, err := utils.GetCategoryPath(id)
+	if err != nil {
+		respondCategoryLookupError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, path)
+}
+
+func UpdateCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		respondCategoryLookupError(c, id, err)
+		return
+	}
+
+	saveCategoryChanges(c, &category, &req.Name, req.ParentID, true)
+}
+
+func PatchCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req PatchCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		respondCategoryLookupError(c, id, err)
+		return
+	}
+
+	saveCategoryChanges(c, &category, req.Name, req.ParentID.Value, req.ParentID.Set)
+}
+
+func DeleteCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	strategy := c.DefaultQuery("strategy", DeleteStrategyReject)
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		respondCategoryLookupError(c, id, err)
+		return
+	}
+
+	var conflict gin.H
+	var err error
+	switch strategy {
+	case DeleteStrategyReject:
+		conflict, err = deleteCategoryReject(category)
+	case DeleteStrategyCascade:
+		conflict, err = deleteCategoryCascade(category)
+	case DeleteStrategyReassign:
+		conflict, err = deleteCategoryReassign(category)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy must be one of reject, cascade or reassign"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if conflict != nil {
+		c.JSON(http.StatusConflict, conflict)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted", "strategy": strategy})
+}
+
+// saveCategoryChanges applies a rename and/or reparent, refusing any parent
+// that would introduce a cycle in the tree.
+func saveCategoryChanges(c *gin.Context, category *models.Category, name *string, parentID *uint, parentSet bool) {
+	if parentSet && parentID != nil {
+		if *parentID == category.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category cannot be its own parent"})
+			return
+		}
+
+		var parentCategory models.Category
+		if err := db.DB.First(&parentCategory, *parentID).Error; err != nil {
+			errorMessage := fmt.Sprintf("Parent category not found with ID: %d", *parentID)
+			c.JSON(http.StatusNotFound, gin.H{"error": errorMessage})
+			return
+		}
+
+		descendantIDs, err := utils.GetAllCategoryIDs(category.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, descendantID := range descendantIDs {
+			if descendantID == *parentID {
+				errorMessage := fmt.Sprintf("Category %d is a descendant of category %d and cannot become its parent", *parentID, category.ID)
+				c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage})
+				return
+			}
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if name != nil {
+		updates["name"] = *name
+	}
+	if parentSet {
+		updates["parent_id"] = parentID
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(category).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var updated models.Category
+	if err := db.DB.Preload("Parent").First(&updated, category.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve category with parent details"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// The deleteCategory* helpers return a non-nil conflict body when the
+// strategy cannot be applied to the category as it stands.
+func deleteCategoryReject(category models.Category) (gin.H, error) {
+	var childCount, productCount int64
+
+	if err := db.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&childCount).Error; err != nil {
+		return nil, err
+	}
+	if err := db.DB.Model(&models.Product{}).Where("category_id = ?", category.ID).Count(&productCount).Error; err != nil {
+		return nil, err
+	}
+
+	if childCount > 0 || productCount > 0 {
+		return gin.H{
+			"error":    "category still has children or products; use strategy=cascade or strategy=reassign",
+			"children": childCount,
+			"products": productCount,
+		}, nil
+	}
+
+	return nil, db.DB.Delete(&category).Error
+}
+
+func deleteCategoryCascade(category models.Category) (gin.H, error) {
+	categoryIDs, err := utils.GetAllCategoryIDs(category.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Products that appear on orders must survive, so refuse rather than
+	// leave order items pointing at nothing.
+	var orderedCount int64
+	err = db.DB.Model(&models.OrderItem{}).
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Where("products.category_id IN ?", categoryIDs).
+		Count(&orderedCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if orderedCount > 0 {
+		return gin.H{"error": "products in this category tree have been ordered and cannot be deleted"}, nil
+	}
+
+	return nil, db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id IN ?", categoryIDs).Delete(&models.Product{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", categoryIDs).Delete(&models.Category{}).Error
+	})
+}
+
+func deleteCategoryReassign(category models.Category) (gin.H, error) {
+	if category.ParentID == nil {
+		var productCount int64
+		if err := db.DB.Model(&models.Product{}).Where("category_id = ?", category.ID).Count(&productCount).Error; err != nil {
+			return nil, err
+		}
+
+		if productCount > 0 {
+			return gin.H{"error": "cannot reassign products of a root category; move them first or use strategy=cascade"}, nil
+		}
+	}
+
+	return nil, db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Category{}).
+			Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error
+		if err != nil {
+			return err
+		}
+
+		if category.ParentID != nil {
+			err = tx.Model(&models.Product{}).
+				Where("category_id = ?", category.ID).
+				Update("category_id", *category.ParentID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&category).Error
+	})
+}
+
+func respondCategoryLookupError(c *gin.Context, id uint, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		errorMessage := fmt.Sprintf("Category not found with ID: %d", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": errorMessage})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// defaultPhoneCountry is assumed for phone numbers given without a country
+// code. main sets it from configuration.
+var defaultPhoneCountry = models.DefaultPhoneCountry
+
+// SetDefaultPhoneCountry changes the country assumed for national-format
+// phone numbers.
+func SetDefaultPhoneCountry(country string) {
+	defaultPhoneCountry = country
+}
+
+// GetProfile returns the signed-in customer, as loaded by RequireAuth.
+func GetProfile(c *gin.Context) {
+	customer, ok := c.Get("customer")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// UpdateProfileRequest carries the profile fields a customer may change.
+// Omitted fields are left as they are.
+type UpdateProfileRequest struct {
+	Name   *string `json:"name"`
+	Phone  *string `json:"phone"`
+	Locale *string `json:"locale" binding:"omitempty,max=16"`
+}
+
+// UpdateProfile lets the signed-in customer change their name, phone number
+// and notification language. Phone numbers are stored in E.164 form.
+func UpdateProfile(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+
+	if req.Phone != nil {
+		phone, err := models.NormalizePhone(*req.Phone, defaultPhoneCountry)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["phone"] = phone
+	}
+
+	if req.Locale != nil {
+		updates["locale"] = strings.TrimSpace(*req.Locale)
+	}
+
+	var customer models.Customer
+	if err := db.DB.First(&customer, custID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(&customer).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// IdentityResponse describes a provider login linked to the customer.
+type IdentityResponse struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// errLastIdentity stops a customer unlinking the only login they have.
+var errLastIdentity = errors.New("cannot unlink your only login")
+
+// ListIdentities lists the provider logins linked to the signed-in
+// customer. Another is linked by signing in with that provider while
+// signed in.
+func ListIdentities(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var rows []models.CustomerIdentity
+	if err := db.DB.Where("customer_id = ?", custID).Order("id").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list := make([]IdentityResponse, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, IdentityResponse{
+			ID:          row.ID,
+			Provider:    row.Provider,
+			Email:       row.Email,
+			CreatedAt:   row.CreatedAt,
+			LastLoginAt: row.LastLoginAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": list})
+}
+
+// UnlinkIdentity removes one of the customer's provider logins, so it no
+// longer signs in to this account. The last one cannot be removed.
+func UnlinkIdentity(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var identities []models.CustomerIdentity
+		if err := db.ForUpdate(tx).Where("customer_id = ?", custID).Find(&identities).Error; err != nil {
+			return err
+		}
+
+		for _, identity := range identities {
+			if identity.ID != id {
+				continue
+			}
+			if len(identities) == 1 {
+				return errLastIdentity
+			}
+			return tx.Delete(&identity).Error
+		}
+		return gorm.ErrRecordNotFound
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "identity not found"})
+	case errors.Is(err, errLastIdentity):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "identity unlinked"})
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// IdempotencyKeyTTL is how long a stored response can be replayed, and
+// IdempotencyLockTTL how long a request holds its key before a retry may
+// assume it died and take the key over.
+var (
+	IdempotencyKeyTTL  = 24 * time.Hour
+	IdempotencyLockTTL = time.Minute
+)
+
+// bodyRecorder tees everything written to the client into a buffer.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a route safe to retry. When the client sends an
+// Idempotency-Key header the key and a hash of the request body are stored
+// per customer; a retry with the same key and body within IdempotencyKeyTTL
+// gets the original response back without the handler running again, and a
+// retry with a different body is refused with 422. Requests without the
+// header pass straight through. Server errors and panics are not
+// remembered so they can be retried, and a request that dies without
+// answering only holds its key for IdempotencyLockTTL.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		custID, ok := currentCustomerID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := hashRequestBody(body)
+
+		record, err := claimIdempotencyKey(custID, key, requestHash)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if record != nil {
+			switch {
+			case record.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+			case record.ResponseStatus == 0:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Release the key if the handler panics, so retries are not told
+		// the request is still in flight.
+		finished := false
+		defer func() {
+			if !finished {
+				releaseIdempotencyKey(custID, key)
+			}
+		}()
+
+		c.Next()
+		finished = true
+
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(custID, key)
+			return
+		}
+
+		err = db.DB.Model(&models.IdempotencyKey{}).
+			Where("customer_id = ? AND key = ?", custID, key).
+			Updates(map[string]interface{}{
+				"response_status": recorder.Status(),
+				"response_body":   recorder.body.Bytes(),
+				"locked_until":    nil,
+			}).Error
+		if err != nil {
+			log.Printf("idempotency: recording response for key %q: %v", key, err)
+		}
+	}
+}
+
+// releaseIdempotencyKey forgets a claim whose request did not complete, so
+// it can be retried.
+func releaseIdempotencyKey(custID uint, key string) {
+	err := db.DB.Where("customer_id = ? AND key = ?", custID, key).Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		log.Printf("idempotency: releasing key %q: %v", key, err)
+	}
+}
+
+// claimIdempotencyKey stores an in-flight record for the key and returns
+// nil, or returns the live record already holding the key. Expired records,
+// and in-flight records whose lock has run out, are discarded and the key
+// claimed afresh.
+func claimIdempotencyKey(custID uint, key, requestHash string) (*models.IdempotencyKey, error) {
+	now := time.Now()
+
+	var existing models.IdempotencyKey
+	err := db.DB.Where("customer_id = ? AND key = ?", custID, key).First(&existing).Error
+	switch {
+	case err == nil && existing.ExpiresAt.After(now) && !staleIdempotencyClaim(existing, now):
+		return &existing, nil
+	case err == nil:
+		if err := db.DB.Delete(&existing).Error; err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	lockedUntil := now.Add(IdempotencyLockTTL)
+	record := models.IdempotencyKey{
+		CustomerID:  custID,
+		Key:         key,
+		RequestHash: requestHash,
+		LockedUntil: &lockedUntil,
+		ExpiresAt:   now.Add(IdempotencyKeyTTL),
+	}
+	if err := db.DB.Create(&record).Error; err != nil {
+		// Lost a race with a concurrent request using the same key.
+		if err := db.DB.Where("customer_id = ? AND key = ?", custID, key).First(&existing).Error; err == nil {
+			return &existing, nil
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// staleIdempotencyClaim reports whether record is still in flight long
+// after its lock ran out, which means the request holding it died.
+func staleIdempotencyClaim(record models.IdempotencyKey, now time.Time) bool {
+	return record.ResponseStatus == 0 && (record.LockedUntil == nil || !record.LockedUntil.After(now))
+}
+
+// hashRequestBody hashes a canonical form of a JSON body, so retries that
+// only differ in whitespace or key order still match.
+func hashRequestBody(body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// currentCustomerID returns the signed-in customer's ID, preferring the
+// customer RequireAuth put on the context and falling back to the session.
+func currentCustomerID(c *gin.Context) (uint, bool) {
+	if value, ok := c.Get("customer"); ok {
+		if customer, ok := value.(*models.Customer); ok && customer.ID != 0 {
+			return customer.ID, true
+		}
+	}
+
+	custID, ok := sessions.Default(c).Get("customer_id").(uint)
+	if !ok || custID == 0 {
+		return 0, false
+	}
+
+	return custID, true
+}
+
+// actorFromContext names whoever is making the request for audit records,
+// preferring an API key, then the staff user, then the customer, and
+// falling back to "system" when no one is authenticated.
+func actorFromContext(c *gin.Context) string {
+	if value, ok := c.Get("api_key"); ok {
+		if key, ok := value.(*models.APIKey); ok && key.ID != 0 {
+			return fmt.Sprintf("api_key:%d", key.ID)
+		}
+	}
+
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*models.User); ok && user.ID != 0 {
+			return fmt.Sprintf("user:%d", user.ID)
+		}
+	}
+
+	if custID, ok := currentCustomerID(c); ok {
+		return fmt.Sprintf("customer:%d", custID)
+	}
+
+	return "system"
+}
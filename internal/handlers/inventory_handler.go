@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+var errInsufficientStock = errors.New("insufficient stock")
+
+type RestockRequest struct {
+	Quantity int    `json:"quantity" binding:"required,gt=0"`
+	Note     string `json:"note"`
+}
+
+type StockAdjustmentRequest struct {
+	Change int    `json:"change" binding:"required,ne=0"`
+	Note   string `json:"note" binding:"required"`
+}
+
+// RestockProduct adds received stock to a product.
+func RestockProduct(c *gin.Context) {
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changeProductStock(c, req.Quantity, models.InventoryReasonRestock, req.Note)
+}
+
+// AdjustProductStock applies a signed correction, e.g. after a stock take
+// or for damaged goods. A note explaining the adjustment is required.
+func AdjustProductStock(c *gin.Context) {
+	var req StockAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changeProductStock(c, req.Change, models.InventoryReasonAdjustment, req.Note)
+}
+
+// ListStockMovements returns a product's inventory ledger, newest first.
+func ListStockMovements(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, id).Error; err != nil {
+		respondProductLookupError(c, id, err)
+		return
+	}
+
+	var movements []models.InventoryMovement
+	if err := db.DB.Where("product_id = ?", id).Order("id DESC").Find(&movements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product_id": id, "stock": product.Stock, "movements": movements})
+}
+
+func changeProductStock(c *gin.Context, change int, reason, note string) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var movement models.InventoryMovement
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := db.ForUpdate(tx).First(&product, id).Error; err != nil {
+			return err
+		}
+
+		var err error
+		movement, err = applyStockChange(tx, product, change, reason, nil, note)
+		return err
+	})
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, movement)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondProductLookupError(c, id, err)
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "adjustment would take stock below zero"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// applyStockChange moves a product's stock by change and writes the matching
+// ledger entry. product must already be locked within tx. The conditional
+// UPDATE is a second guard against going negative on databases without row
+// locks.
+func applyStockChange(tx *gorm.DB, product models.Product, change int, reason string, orderID *uint, note string) (models.InventoryMovement, error) {
+	balance := product.Stock + change
+	if balance < 0 {
+		return models.InventoryMovement{}, errInsufficientStock
+	}
+
+	result := tx.Model(&models.Product{}).
+		Where("id = ? AND stock + ? >= 0", product.ID, change).
+		Update("stock", gorm.Expr("stock + ?", change))
+	if result.Error != nil {
+		return models.InventoryMovement{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.InventoryMovement{}, errInsufficientStock
+	}
+
+	movement := models.InventoryMovement{
+		ProductID:    product.ID,
+		Change:       change,
+		BalanceAfter: balance,
+		Reason:       reason,
+		OrderID:      orderID,
+		Note:         note,
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return models.InventoryMovement{}, err
+	}
+
+	return movement, nil
+}
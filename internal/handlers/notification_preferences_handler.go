@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+)
+
+// NotificationPreferenceSetting is one channel/event switch as shown to and
+// set by the customer.
+type NotificationPreferenceSetting struct {
+	Channel string `json:"channel" binding:"required"`
+	Event   string `json:"event" binding:"required"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceSetting `json:"preferences" binding:"required,dive"`
+}
+
+// notificationSettings maps channel and event to whether the customer wants
+// to be notified. Every known pair is present; those without a stored
+// preference are enabled.
+type notificationSettings map[string]map[string]bool
+
+func (s notificationSettings) enabled(channel, event string) bool {
+	return s[channel][event]
+}
+
+func loadNotificationSettings(tx *gorm.DB, customerID uint) (notificationSettings, error) {
+	settings := make(notificationSettings, len(models.NotificationChannels))
+	for _, channel := range models.NotificationChannels {
+		settings[channel] = make(map[string]bool, len(models.NotificationEvents))
+		for _, event := range models.NotificationEvents {
+			settings[channel][event] = true
+		}
+	}
+
+	var prefs []models.NotificationPreference
+	if err := tx.Where("customer_id = ?", customerID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, pref := range prefs {
+		if events, ok := settings[pref.Channel]; ok {
+			if _, ok := events[pref.Event]; ok {
+				events[pref.Event] = pref.Enabled
+			}
+		}
+	}
+
+	return settings, nil
+}
+
+func (s notificationSettings) list() []NotificationPreferenceSetting {
+	list := make([]NotificationPreferenceSetting, 0, len(models.NotificationChannels)*len(models.NotificationEvents))
+	for _, channel := range models.NotificationChannels {
+		for _, event := range models.NotificationEvents {
+			enabled := s[channel][event]
+			list = append(list, NotificationPreferenceSetting{Channel: channel, Event: event, Enabled: &enabled})
+		}
+	}
+	return list
+}
+
+// setNotificationPreferences stores the given switches, creating or
+// updating one row per channel/event.
+func setNotificationPreferences(tx *gorm.DB, customerID uint, settings []NotificationPreferenceSetting) error {
+	for _, setting := range settings {
+		pref := models.NotificationPreference{
+			CustomerID: customerID,
+			Channel:    setting.Channel,
+			Event:      setting.Event,
+			Enabled:    *setting.Enabled,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "customer_id"}, {Name: "channel"}, {Name: "event"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&pref).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotificationPreferences lists, for every channel and event, whether
+// the signed-in customer receives that notification.
+func GetNotificationPreferences(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	settings, err := loadNotificationSettings(db.DB, custID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": settings.list()})
+}
+
+// UpdateNotificationPreferences turns the given channel/event notifications
+// on or off for the signed-in customer. Pairs not mentioned are left as they
+// are.
+func UpdateNotificationPreferences(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, setting := range req.Preferences {
+		if !models.IsValidNotificationChannel(setting.Channel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown notification channel: %s", setting.Channel)})
+			return
+		}
+		if !models.IsValidNotificationEvent(setting.Event) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown notification event: %s", setting.Event)})
+			return
+		}
+	}
+
+	var settings notificationSettings
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setNotificationPreferences(tx, custID, req.Preferences); err != nil {
+			return err
+		}
+
+		var err error
+		settings, err = loadNotificationSettings(tx, custID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": settings.list()})
+}
+
+// unsubscribeConfirmPage is shown when the link in an email is opened. Mail
+// scanners and link previews follow links too, so opening it changes nothing
+// until the form is submitted.
+var unsubscribeConfirmPage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<p>Stop receiving these {{.Channel}} notifications?</p>
+<ul>{{range .Events}}<li>{{.}}</li>{{end}}</ul>
+<form method="post" action="/unsubscribe">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Unsubscribe</button>
+</form>
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribe handles the link at the bottom of notification emails.
+// It checks the signed ?token= and renders a form that POSTs it back to
+// Unsubscribe.
+func ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	claims, events, ok := verifyUnsubscribeToken(c, token)
+	if !ok {
+		return
+	}
+
+	c.Render(http.StatusOK, render.HTML{
+		Template: unsubscribeConfirmPage,
+		Data: gin.H{
+			"Token":   token,
+			"Channel": claims.Channel,
+			"Events":  events,
+		},
+	})
+}
+
+// Unsubscribe turns off the notifications named by a signed unsubscribe
+// token, given as ?token= (RFC 8058 one-click) or as the token form field
+// posted from ConfirmUnsubscribe. It is public, so no sign-in is needed;
+// repeating it is harmless.
+func Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+
+	claims, events, ok := verifyUnsubscribeToken(c, token)
+	if !ok {
+		return
+	}
+
+	disabled := false
+	settings := make([]NotificationPreferenceSetting, 0, len(events))
+	for _, event := range events {
+		settings = append(settings, NotificationPreferenceSetting{Channel: claims.Channel, Event: event, Enabled: &disabled})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.Customer{}, claims.CustomerID).Error; err != nil {
+			return err
+		}
+		return setNotificationPreferences(tx, claims.CustomerID, settings)
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "unsubscribed", "channel": claims.Channel, "events": events})
+	}
+}
+
+// verifyUnsubscribeToken checks an unsubscribe token and returns its claims
+// and the events it turns off. Otherwise it writes the error response and
+// returns false.
+func verifyUnsubscribeToken(c *gin.Context, token string) (unsubscribeClaims, []string, bool) {
+	var claims unsubscribeClaims
+
+	if len(unsubscribeSecret) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "unsubscribe links are not configured"})
+		return claims, nil, false
+	}
+
+	if err := utils.VerifyToken(unsubscribeSecret, token, &claims); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unsubscribe token"})
+		return claims, nil, false
+	}
+
+	if !models.IsValidNotificationChannel(claims.Channel) || (claims.Event != "" && !models.IsValidNotificationEvent(claims.Event)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unsubscribe token"})
+		return claims, nil, false
+	}
+
+	if claims.expired(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "unsubscribe link has expired"})
+		return claims, nil, false
+	}
+
+	if claims.Event != "" {
+		return claims, []string{claims.Event}, true
+	}
+	return claims, models.NotificationEvents, true
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+)
+
+// templates renders customer notifications. It starts with the built-in
+// templates; main swaps in a set loaded with any configured overrides.
+var templates = notifier.DefaultTemplates()
+
+// Unsubscribe links are only put in emails once main has configured where
+// the API is reachable and the secret to sign them with.
+var (
+	unsubscribeBaseURL string
+	unsubscribeSecret  []byte
+)
+
+// UnsubscribeLinkTTL is how long an unsubscribe link keeps working after the
+// email carrying it was sent.
+var UnsubscribeLinkTTL = 90 * 24 * time.Hour
+
+// SetTemplates replaces the notification templates used by the handlers.
+func SetTemplates(t *notifier.Templates) {
+	templates = t
+}
+
+// SetUnsubscribeLinks configures the public base URL and signing secret for
+// unsubscribe links. An empty secret turns the links off.
+func SetUnsubscribeLinks(baseURL string, secret string) {
+	unsubscribeBaseURL = strings.TrimRight(baseURL, "/")
+	unsubscribeSecret = []byte(secret)
+}
+
+// unsubscribeClaims is what an unsubscribe token vouches for. An empty
+// Event stands for every event on Channel. IssuedAt is in Unix seconds.
+type unsubscribeClaims struct {
+	CustomerID uint   `json:"c"`
+	Channel    string `json:"ch"`
+	Event      string `json:"e,omitempty"`
+	IssuedAt   int64  `json:"iat"`
+}
+
+func (claims unsubscribeClaims) expired(now time.Time) bool {
+	return now.After(time.Unix(claims.IssuedAt, 0).Add(UnsubscribeLinkTTL))
+}
+
+// unsubscribeURL returns a link that turns off event on channel for the
+// customer without signing in, or "" when links are not configured.
+func unsubscribeURL(customerID uint, channel, event string) (string, error) {
+	if len(unsubscribeSecret) == 0 || unsubscribeBaseURL == "" {
+		return "", nil
+	}
+
+	token, err := utils.SignToken(unsubscribeSecret, unsubscribeClaims{
+		CustomerID: customerID,
+		Channel:    channel,
+		Event:      event,
+		IssuedAt:   time.Now().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/unsubscribe?token=%s", unsubscribeBaseURL, url.QueryEscape(token)), nil
+}
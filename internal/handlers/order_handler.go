@@ -1,26 +1,84 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
-	"github.com/gin-contrib/sessions"
+	"gorm.io/gorm"
 
 	"github.com/Keoroanthony/go-ecommerce/internal/db"
     "github.com/Keoroanthony/go-ecommerce/internal/models"
 	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+	"github.com/Keoroanthony/go-ecommerce/internal/outbox"
 )
 
+// maxOrderItemQuantity caps a single line so a typo cannot place an
+// absurdly large order.
+const maxOrderItemQuantity = 1000
+
+// StockShortage describes one order line that could not be filled.
+type StockShortage struct {
+    ProductID uint   `json:"product_id"`
+    Name      string `json:"name"`
+    Requested uint   `json:"requested"`
+    Available int    `json:"available"`
+}
+
+type OrderItemRequest struct {
+    ProductID uint `json:"product_id"`
+    Quantity  uint `json:"quantity"`
+}
+
+// CreateOrderRequest accepts line items with quantities. The older
+// product_ids form is still honoured, each ID counting as a quantity of one.
 type CreateOrderRequest struct {
-    ProductIDs []uint `json:"product_ids"`
+    Items      []OrderItemRequest `json:"items"`
+    ProductIDs []uint             `json:"product_ids"`
+}
+
+// mergeOrderLines validates the requested lines and folds repeated products
+// into a single line, keeping the order in which products first appear.
+func mergeOrderLines(req CreateOrderRequest) ([]OrderItemRequest, error) {
+	requested := make([]OrderItemRequest, 0, len(req.Items)+len(req.ProductIDs))
+	requested = append(requested, req.Items...)
+	for _, productID := range req.ProductIDs {
+		requested = append(requested, OrderItemRequest{ProductID: productID, Quantity: 1})
+	}
+
+	var lines []OrderItemRequest
+	position := make(map[uint]int)
+
+	for _, item := range requested {
+		if item.ProductID == 0 {
+			return nil, fmt.Errorf("product_id is required for every item")
+		}
+		if item.Quantity < 1 || item.Quantity > maxOrderItemQuantity {
+			return nil, fmt.Errorf("quantity for product %d must be between 1 and %d", item.ProductID, maxOrderItemQuantity)
+		}
+
+		if i, seen := position[item.ProductID]; seen {
+			lines[i].Quantity += item.Quantity
+			if lines[i].Quantity > maxOrderItemQuantity {
+				return nil, fmt.Errorf("quantity for product %d must be between 1 and %d", item.ProductID, maxOrderItemQuantity)
+			}
+			continue
+		}
+
+		position[item.ProductID] = len(lines)
+		lines = append(lines, item)
+	}
+
+	return lines, nil
 }
 
 func CreateOrder (c *gin.Context) {
 
-	sess := sessions.Default(c)
-	custID, ok := sess.Get("customer_id").(uint)
+	custID, ok := currentCustomerID(c)
 
-	if !ok || custID == 0 {
+	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
@@ -32,11 +90,17 @@ func CreateOrder (c *gin.Context) {
         return
     }
 
-	if len(req.ProductIDs) == 0 {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "product_ids required"})
+	if len(req.Items) == 0 && len(req.ProductIDs) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "items or product_ids required"})
         return
     }
 
+	lines, err := mergeOrderLines(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var customer models.Customer
     if err := db.DB.First(&customer, custID).Error; err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "customer not found"})
@@ -53,6 +117,7 @@ func CreateOrder (c *gin.Context) {
 	order := models.Order{
 
         CustomerID: customer.ID,
+        Status:     models.OrderStatusPending,
     }
 
 	if err := tx.Create(&order).Error; err != nil {
@@ -63,31 +128,110 @@ func CreateOrder (c *gin.Context) {
         return
     }
 
+	placed := models.OrderStatusHistory{
+		OrderID:  order.ID,
+		ToStatus: models.OrderStatusPending,
+		Actor:    fmt.Sprintf("customer:%d", customer.ID),
+	}
+
+	if err := tx.Create(&placed).Error; err != nil {
+
+		tx.Rollback()
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record order status"})
+		return
+	}
+
+	productIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	// Lock every product row up front, in ID order, so concurrent orders
+	// for overlapping products cannot deadlock or oversell.
+	var lockedProducts []models.Product
+	if err := db.ForUpdate(tx).Where("id IN ?", productIDs).Order("id").Find(&lockedProducts).Error; err != nil {
+
+		tx.Rollback()
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	products := make(map[uint]models.Product, len(lockedProducts))
+	for _, product := range lockedProducts {
+		products[product.ID] = product
+	}
+
     var orderItems []models.OrderItem
-	var totalOrderPrice float64
+	var totalOrderPrice models.Money
+	var shortages []StockShortage
 
-	for _, productID := range req.ProductIDs {
+	for _, line := range lines {
 
-		var product models.Product
+		product, found := products[line.ProductID]
 
-		if err := tx.First(&product, productID).Error; err != nil {
+		if !found {
 
 			tx.Rollback()
 		
-			errorMessage := fmt.Sprintf("Product not found with ID: %d", productID)
+			errorMessage := fmt.Sprintf("Product not found with ID: %d", line.ProductID)
 			c.JSON(http.StatusNotFound, gin.H{"error": errorMessage})
 			return
 		}
 
+		if product.Stock < int(line.Quantity) {
+			shortages = append(shortages, StockShortage{
+				ProductID: product.ID,
+				Name:      product.Name,
+				Requested: line.Quantity,
+				Available: product.Stock,
+			})
+			continue
+		}
+
 		orderItem := models.OrderItem{
 			OrderID:   order.ID, 
 			ProductID: product.ID,
-			Quantity:  1,
+			Quantity:  line.Quantity,
 			Price:     product.Price,
 		}
 
+		if len(orderItems) == 0 {
+			totalOrderPrice = models.NewMoney(0, product.Price.Currency)
+		}
+
+		total, err := totalOrderPrice.Add(product.Price.Mul(int64(line.Quantity)))
+		if err != nil {
+
+			tx.Rollback()
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": "all products in an order must be priced in the same currency"})
+			return
+		}
+
 		orderItems = append(orderItems, orderItem)
-		totalOrderPrice += product.Price
+		totalOrderPrice = total
+	}
+
+	if len(shortages) > 0 {
+
+		tx.Rollback()
+
+		c.JSON(http.StatusConflict, gin.H{"error": "insufficient stock", "items": shortages})
+		return
+	}
+
+	for _, line := range lines {
+		orderID := order.ID
+		_, err := applyStockChange(tx, products[line.ProductID], -int(line.Quantity), models.InventoryReasonOrder, &orderID, "")
+		if err != nil {
+
+			tx.Rollback()
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reserve stock"})
+			return
+		}
 	}
 
 	if len(orderItems) > 0 {
@@ -100,28 +244,329 @@ func CreateOrder (c *gin.Context) {
 		}
 	}
 	
+	// Notifications go through the outbox so they are only sent for orders
+	// that commit, and survive a restart or a provider outage.
+	if err := queueOrderPlacedNotifications(tx, customer, order.ID, orderItems, products, totalOrderPrice); err != nil {
+
+		tx.Rollback()
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue order notifications"})
+		return
+	}
+
 	if err := tx.Preload("Items").First(&order, order.ID).Error; err != nil {
+
+		tx.Rollback()
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve order with items"})
 		return
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create order"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "order created successfully", "order": order})
+
+
+}
 
-	go func(customer models.Customer, order models.Order, totalOrderPrice float64) {
+// queueOrderPlacedNotifications renders the order confirmation in the
+// customer's language and writes it to the outbox within tx, skipping
+// channels the customer has opted out of or has no address for.
+func queueOrderPlacedNotifications(tx *gorm.DB, customer models.Customer, orderID uint, items []models.OrderItem, products map[uint]models.Product, total models.Money) error {
+	data := notifier.OrderPlacedData{
+		CustomerName: customer.Name,
+		OrderID:      orderID,
+		Total:        total,
+	}
+	for _, item := range items {
+		data.Items = append(data.Items, notifier.OrderLine{
+			Name:      products[item.ProductID].Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			LineTotal: item.Price.Mul(int64(item.Quantity)),
+		})
+	}
+
+	settings, err := loadNotificationSettings(tx, customer.ID)
+	if err != nil {
+		return err
+	}
 
-		if err := notifier.SendSMS(customer.Phone, order.ID, totalOrderPrice); err != nil {
-			fmt.Printf("Failed to send SMS for order %d to %s: %v\n", order.ID, customer.Phone, err)
+	if customer.Phone != "" && settings.enabled(models.OutboxChannelSMS, models.NotificationEventOrderPlaced) {
+		sms, err := templates.OrderPlacedSMS(customer.Phone, customer.Locale, data)
+		if err != nil {
+			return err
+		}
+		if err := outbox.EnqueueSMS(tx, sms, &orderID); err != nil {
+			return err
 		}
-	}(customer, order, totalOrderPrice)
+	}
 
-	go func(customer models.Customer, order models.Order, totalOrderPrice float64) {
+	if customer.Email != "" && settings.enabled(models.OutboxChannelEmail, models.NotificationEventOrderPlaced) {
+		data.UnsubscribeURL, err = unsubscribeURL(customer.ID, models.OutboxChannelEmail, models.NotificationEventOrderPlaced)
+		if err != nil {
+			return err
+		}
 
-		if err := notifier.SendEmail(customer.Email, customer.Name, order.ID, totalOrderPrice); err != nil {
-			fmt.Printf("Failed to send SMS for order %d to %s: %v\n", order.ID, customer.Phone, err)
+		email, err := templates.OrderPlacedEmail(customer.Email, customer.Locale, data)
+		if err != nil {
+			return err
+		}
+		if err := outbox.EnqueueEmail(tx, email, &orderID); err != nil {
+			return err
 		}
-	}(customer, order, totalOrderPrice)
+	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "order created successfully", "order": order})
+	return nil
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// UpdateOrderStatus moves an order along the status state machine and
+// records the change in the order's history. Cancelling an order returns
+// its items to stock.
+func UpdateOrderStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
 
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-}
\ No newline at end of file
+	if !models.IsValidOrderStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown order status: %s", req.Status)})
+		return
+	}
+
+	var order models.Order
+	var conflict gin.H
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).Preload("Items").First(&order, id).Error; err != nil {
+			return err
+		}
+
+		if !models.CanTransitionOrder(order.Status, req.Status) {
+			conflict = gin.H{
+				"error":   fmt.Sprintf("cannot move order from %s to %s", order.Status, req.Status),
+				"allowed": models.AllowedOrderTransitions(order.Status),
+			}
+			return nil
+		}
+
+		history := models.OrderStatusHistory{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   req.Status,
+			Actor:      actorFromContext(c),
+			Note:       req.Note,
+		}
+
+		if err := tx.Model(&order).Update("status", req.Status).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		if req.Status == models.OrderStatusCancelled {
+			return releaseOrderStock(tx, order)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Order not found with ID: %d", id)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if conflict != nil {
+		c.JSON(http.StatusConflict, conflict)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// releaseOrderStock puts a cancelled order's items back into stock.
+func releaseOrderStock(tx *gorm.DB, order models.Order) error {
+	for _, item := range order.Items {
+		var product models.Product
+		if err := db.ForUpdate(tx).First(&product, item.ProductID).Error; err != nil {
+			return err
+		}
+
+		orderID := order.ID
+		if _, err := applyStockChange(tx, product, int(item.Quantity), models.InventoryReasonCancelled, &orderID, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListOrders returns the signed-in customer's orders, newest first. It
+// supports status, from/to (RFC 3339 timestamps or YYYY-MM-DD dates, to
+// being exclusive), limit and the opaque cursor from next_cursor.
+func ListOrders(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	listOrderPage(c, db.DB.Model(&models.Order{}).Where("orders.customer_id = ?", custID))
+}
+
+// ListAllOrders is the back-office and integration view of every order,
+// newest first, with the same filters as ListOrders plus ?customer_id=.
+func ListAllOrders(c *gin.Context) {
+	query := db.DB.Model(&models.Order{})
+
+	if customerIDParam := c.Query("customer_id"); customerIDParam != "" {
+		var customerID uint
+		if _, err := fmt.Sscan(customerIDParam, &customerID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return
+		}
+		query = query.Where("orders.customer_id = ?", customerID)
+	}
+
+	listOrderPage(c, query)
+}
+
+// listOrderPage applies the ?status=, ?from=, ?to= and paging parameters to
+// query and responds with one page of orders.
+func listOrderPage(c *gin.Context, query *gorm.DB) {
+	limit, err := parsePageLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	const sort = "-created"
+	cursor, err := decodeCursor(c.Query("cursor"), sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query = query.Preload("Items.Product")
+
+	if status := c.Query("status"); status != "" {
+		if !models.IsValidOrderStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown order status: %s", status)})
+			return
+		}
+		query = query.Where("orders.status = ?", status)
+	}
+
+	for param, condition := range map[string]string{"from": "orders.created_at >= ?", "to": "orders.created_at < ?"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		bound, err := parseDateParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s", param)})
+			return
+		}
+		query = query.Where(condition, bound)
+	}
+
+	// IDs are assigned in creation order, so id DESC is newest first and
+	// gives a stable keyset.
+	query = applyKeyset(query, "orders", "id", true, cursor)
+
+	var orders []models.Order
+	if err := query.Limit(limit + 1).Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var nextCursor *string
+	if len(orders) > limit {
+		orders = orders[:limit]
+		encoded := encodeCursor(pageCursor{Sort: sort, ID: orders[limit-1].ID})
+		nextCursor = &encoded
+	}
+
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders, "next_cursor": nextCursor})
+}
+
+// GetOrder returns one of the signed-in customer's orders with its items
+// and status history. Orders belonging to anyone else are reported as not
+// found so their existence is not revealed.
+func GetOrder(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	respondWithOrder(c, db.DB.Where("customer_id = ?", custID), id)
+}
+
+// GetAnyOrder is the back-office and integration view of a single order,
+// whoever placed it, with its items and status history.
+func GetAnyOrder(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	respondWithOrder(c, db.DB, id)
+}
+
+// respondWithOrder responds with the order id found by query, with its
+// items and status history, or 404.
+func respondWithOrder(c *gin.Context, query *gorm.DB, id uint) {
+	var order models.Order
+	err := query.
+		Preload("Items.Product").
+		Preload("History", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		First(&order, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Order not found with ID: %d", id)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+var outboxStatuses = map[string]bool{
+	models.OutboxStatusPending: true,
+	models.OutboxStatusSent:    true,
+	models.OutboxStatusDead:    true,
+}
+
+// ListOutboxMessages lists notification deliveries, newest first. It
+// defaults to dead messages, the ones that need attention; pass
+// ?status=pending or ?status=sent to see the rest, and ?order_id= to follow
+// a single order.
+func ListOutboxMessages(c *gin.Context) {
+	limit, err := parsePageLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.OutboxStatusDead)
+	if !outboxStatuses[status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown outbox status: %s", status)})
+		return
+	}
+
+	sort := "-created:" + status
+	cursor, err := decodeCursor(c.Query("cursor"), sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.DB.Model(&models.OutboxMessage{}).Where("outbox_messages.status = ?", status)
+
+	if orderIDParam := c.Query("order_id"); orderIDParam != "" {
+		var orderID uint
+		if _, err := fmt.Sscan(orderIDParam, &orderID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order_id"})
+			return
+		}
+		query = query.Where("outbox_messages.order_id = ?", orderID)
+	}
+
+	query = applyKeyset(query, "outbox_messages", "id", true, cursor)
+
+	var messages []models.OutboxMessage
+	if err := query.Limit(limit + 1).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var nextCursor *string
+	if len(messages) > limit {
+		messages = messages[:limit]
+		encoded := encodeCursor(pageCursor{Sort: sort, ID: messages[limit-1].ID})
+		nextCursor = &encoded
+	}
+
+	if messages == nil {
+		messages = []models.OutboxMessage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "next_cursor": nextCursor})
+}
+
+// RedriveOutboxMessage puts a dead message back in the queue with a fresh
+// set of attempts, e.g. once a provider outage is over.
+func RedriveOutboxMessage(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var message models.OutboxMessage
+	var conflict gin.H
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).First(&message, id).Error; err != nil {
+			return err
+		}
+
+		if message.Status != models.OutboxStatusDead {
+			conflict = gin.H{"error": fmt.Sprintf("only dead messages can be re-driven; message %d is %s", id, message.Status)}
+			return nil
+		}
+
+		return tx.Model(&message).Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Outbox message not found with ID: %d", id)})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case conflict != nil:
+		c.JSON(http.StatusConflict, conflict)
+	default:
+		c.JSON(http.StatusOK, message)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor marks the last row of a page for keyset pagination. Sort pins
+// the cursor to the ordering it was issued for, Value holds that row's sort
+// column and ID breaks ties between rows sharing the same Value.
+type pageCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v,omitempty"`
+	ID    uint        `json:"id"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded, sort string) (*pageCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	if cursor.Sort != sort {
+		return nil, errors.New("cursor was issued for a different sort order")
+	}
+
+	return &cursor, nil
+}
+
+// parsePageLimit reads the limit query parameter, defaulting to
+// defaultPageLimit and capping at maxPageLimit.
+func parsePageLimit(limitParam string) (int, error) {
+	if limitParam == "" {
+		return defaultPageLimit, nil
+	}
+
+	var limit int
+	if _, err := fmt.Sscan(limitParam, &limit); err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return limit, nil
+}
+
+// applyKeyset orders query by column (then id) and, when a cursor is given,
+// restricts it to rows after the cursor in that order.
+func applyKeyset(query *gorm.DB, table, column string, descending bool, cursor *pageCursor) *gorm.DB {
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	idColumn := table + ".id"
+	sortColumn := table + "." + column
+
+	if cursor != nil {
+		if sortColumn == idColumn {
+			query = query.Where(fmt.Sprintf("%s %s ?", idColumn, comparison), cursor.ID)
+		} else {
+			query = query.Where(
+				fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", sortColumn, comparison, sortColumn, idColumn, comparison),
+				cursor.Value, cursor.Value, cursor.ID,
+			)
+		}
+	}
+
+	if sortColumn == idColumn {
+		return query.Order(fmt.Sprintf("%s %s", idColumn, direction))
+	}
+
+	return query.Order(fmt.Sprintf("%s %s", sortColumn, direction)).Order(fmt.Sprintf("%s %s", idColumn, direction))
+}
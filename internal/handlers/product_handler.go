@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/Keoroanthony/go-ecommerce/internal/db"
@@ -12,9 +14,10 @@ import (
 )
 
 type CreateProductRequest struct {
-	Name       string  `json:"name" binding:"required"`
-	Price      float64 `json:"price" binding:"required,gt=0"`
-	CategoryID uint    `json:"category_id" binding:"required"`
+	Name       string       `json:"name" binding:"required"`
+	Price      models.Money `json:"price" binding:"required,gt=0"`
+	CategoryID uint         `json:"category_id" binding:"required"`
+	Stock      int          `json:"stock" binding:"gte=0"`
 }
 
 func CreateProduct(c *gin.Context) {
@@ -42,7 +45,22 @@ func CreateProduct(c *gin.Context) {
 		CategoryID: req.CategoryID,
 	}
 
-	if err := db.DB.Create(&product).Error; err != nil {
+	// Opening stock goes through the ledger like any other movement.
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+
+		if req.Stock == 0 {
+			return nil
+		}
+
+		movement, err := applyStockChange(tx, product, req.Stock, models.InventoryReasonInitial, nil, "")
+		product.Stock = movement.BalanceAfter
+		return err
+	})
+
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,16 +93,280 @@ func GetAveragePrice(c *gin.Context) {
 		return
 	}
 
+	currency := strings.ToUpper(c.DefaultQuery("currency", models.DefaultCurrency))
+
+	// AVG over integer minor units, rounded half away from zero back to a
+	// whole minor unit.
 	var avg float64
 	err = db.DB.
 		Model(&models.Product{}).
-		Where("category_id IN ?", categoryIDs).
-		Select("COALESCE(AVG(price), 0)").
+		Where("category_id IN ? AND price_currency = ?", categoryIDs, currency).
+		Select("COALESCE(AVG(price_amount), 0)").
 		Scan(&avg).Error
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"category_id": categoryID, "average_price": avg})
-}
\ No newline at end of file
+	averagePrice := models.NewMoney(int64(math.Round(avg)), currency)
+
+	c.JSON(http.StatusOK, gin.H{"category_id": categoryID, "average_price": averagePrice})
+}
+
+type UpdateProductRequest struct {
+	Name       string       `json:"name" binding:"required"`
+	Price      models.Money `json:"price" binding:"required,gt=0"`
+	CategoryID uint         `json:"category_id" binding:"required"`
+}
+
+type PatchProductRequest struct {
+	Name       *string       `json:"name" binding:"omitempty,min=1"`
+	Price      *models.Money `json:"price" binding:"omitempty,gt=0"`
+	CategoryID *uint         `json:"category_id" binding:"omitempty,gt=0"`
+}
+
+// productSortColumns maps the ?sort= values accepted by ListProducts to
+// product columns. A leading "-" on the value sorts descending.
+var productSortColumns = map[string]string{
+	"id":    "id",
+	"name":  "name",
+	"price": "price_amount",
+}
+
+func GetProduct(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.Preload("Category").First(&product, id).Error; err != nil {
+		respondProductLookupError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// ListProducts returns a page of products. It supports sort (id, name,
+// price, prefixed with "-" for descending), min_price/max_price as decimal
+// amounts in currency (default KES), category_id (which includes every
+// descendant category), limit and the opaque cursor returned as
+// next_cursor by the previous page.
+func ListProducts(c *gin.Context) {
+	sort := c.DefaultQuery("sort", "id")
+	column, descending := strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if _, ok := productSortColumns[column]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of id, name, price (prefix with - for descending)"})
+		return
+	}
+
+	limit, err := parsePageLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cursor, err := decodeCursor(c.Query("cursor"), sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.DB.Model(&models.Product{}).Preload("Category")
+
+	minPriceParam, maxPriceParam := c.Query("min_price"), c.Query("max_price")
+	if currency := c.Query("currency"); currency != "" || minPriceParam != "" || maxPriceParam != "" {
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		query = query.Where("products.price_currency = ?", strings.ToUpper(currency))
+
+		if minPriceParam != "" {
+			minPrice, err := models.ParseMoney(minPriceParam, currency)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_price"})
+				return
+			}
+			query = query.Where("products.price_amount >= ?", minPrice.Amount)
+		}
+
+		if maxPriceParam != "" {
+			maxPrice, err := models.ParseMoney(maxPriceParam, currency)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_price"})
+				return
+			}
+			query = query.Where("products.price_amount <= ?", maxPrice.Amount)
+		}
+	}
+
+	if categoryIDParam := c.Query("category_id"); categoryIDParam != "" {
+		var categoryID uint
+		if _, err := fmt.Sscan(categoryIDParam, &categoryID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
+			return
+		}
+
+		categoryIDs, err := utils.GetAllCategoryIDs(categoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("products.category_id IN ?", categoryIDs)
+	}
+
+	query = applyKeyset(query, "products", productSortColumns[column], descending, cursor)
+
+	var products []models.Product
+	if err := query.Limit(limit + 1).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var nextCursor *string
+	if len(products) > limit {
+		products = products[:limit]
+		last := products[limit-1]
+
+		next := pageCursor{Sort: sort, ID: last.ID}
+		switch column {
+		case "name":
+			next.Value = last.Name
+		case "price":
+			next.Value = last.Price.Amount
+		}
+
+		encoded := encodeCursor(next)
+		nextCursor = &encoded
+	}
+
+	if products == nil {
+		products = []models.Product{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": products, "next_cursor": nextCursor})
+}
+
+func UpdateProduct(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saveProductChanges(c, id, map[string]interface{}{
+		"name":           req.Name,
+		"price_amount":   req.Price.Amount,
+		"price_currency": req.Price.Currency,
+		"category_id":    req.CategoryID,
+	})
+}
+
+func PatchProduct(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req PatchProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Price != nil {
+		updates["price_amount"] = req.Price.Amount
+		updates["price_currency"] = req.Price.Currency
+	}
+	if req.CategoryID != nil {
+		updates["category_id"] = *req.CategoryID
+	}
+
+	saveProductChanges(c, id, updates)
+}
+
+func DeleteProduct(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, id).Error; err != nil {
+		respondProductLookupError(c, id, err)
+		return
+	}
+
+	var orderedCount int64
+	if err := db.DB.Model(&models.OrderItem{}).Where("product_id = ?", id).Count(&orderedCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if orderedCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "product has been ordered and cannot be deleted"})
+		return
+	}
+
+	if err := db.DB.Delete(&product).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "product deleted"})
+}
+
+func saveProductChanges(c *gin.Context, id uint, updates map[string]interface{}) {
+	var product models.Product
+	if err := db.DB.First(&product, id).Error; err != nil {
+		respondProductLookupError(c, id, err)
+		return
+	}
+
+	if categoryID, ok := updates["category_id"].(uint); ok {
+		var category models.Category
+		if err := db.DB.First(&category, categoryID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				errorMessage := fmt.Sprintf("Category not found with ID: %d", categoryID)
+				c.JSON(http.StatusNotFound, gin.H{"error": errorMessage})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking category existence"})
+			}
+			return
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(&product).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := db.DB.Preload("Category").First(&product, product.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve Product with Category details"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func respondProductLookupError(c *gin.Context, id uint, err error) {
+	if err == gorm.ErrRecordNotFound {
+		errorMessage := fmt.Sprintf("Product not found with ID: %d", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": errorMessage})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// SessionResponse describes one of the customer's signed-in sessions.
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// currentSessionTokenHash returns the stored hash of the request's session
+// token, or "" when the request has no server-side session.
+func currentSessionTokenHash(c *gin.Context) string {
+	if _, ok := c.Get(sessions.DefaultKey); !ok {
+		return ""
+	}
+	token := sessions.Default(c).ID()
+	if token == "" {
+		return ""
+	}
+	return models.HashSessionToken(token)
+}
+
+// ListSessions lists the signed-in customer's active sessions, most
+// recently used first, marking the one making the request.
+func ListSessions(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var rows []models.Session
+	err := db.DB.Where("customer_id = ? AND expires_at > ?", custID, time.Now()).
+		Order("last_seen_at DESC").Order("id DESC").
+		Find(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	current := currentSessionTokenHash(c)
+	list := make([]SessionResponse, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, SessionResponse{
+			ID:         row.ID,
+			UserAgent:  row.UserAgent,
+			CreatedAt:  row.CreatedAt,
+			LastSeenAt: row.LastSeenAt,
+			ExpiresAt:  row.ExpiresAt,
+			Current:    current != "" && row.TokenHash == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": list})
+}
+
+// RevokeSession signs one of the customer's sessions out. Revoking the
+// current session works like logging out.
+func RevokeSession(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	result := db.DB.Where("id = ? AND customer_id = ?", id, custID).Delete(&models.Session{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeOtherSessions signs the customer out everywhere except the session
+// making the request.
+func RevokeOtherSessions(c *gin.Context) {
+	custID, ok := currentCustomerID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	query := db.DB.Where("customer_id = ?", custID)
+	if current := currentSessionTokenHash(c); current != "" {
+		query = query.Where("token_hash <> ?", current)
+	}
+
+	result := query.Delete(&models.Session{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked", "revoked": result.RowsAffected})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// SMSDeliveryReport is the form Africa's Talking posts to the delivery
+// report callback URL.
+type SMSDeliveryReport struct {
+	ID            string `form:"id" binding:"required"`
+	Status        string `form:"status" binding:"required"`
+	PhoneNumber   string `form:"phoneNumber"`
+	NetworkCode   string `form:"networkCode"`
+	FailureReason string `form:"failureReason"`
+	RetryCount    int    `form:"retryCount"`
+}
+
+// SMSDeliveryCallback receives Africa's Talking delivery reports and
+// updates the matching SMSDelivery. The route is public, so when token is
+// non-empty the request must carry it as ?token=. A report that would
+// replace a final status with an intermediate one, e.g. a late "Buffered"
+// after "Success", is acknowledged but ignored, as is a report for a
+// message ID we have no record of, e.g. an SMS sent before deliveries were
+// recorded: Africa's Talking retries any callback not answered with 2xx.
+func SMSDeliveryCallback(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid callback token"})
+			return
+		}
+
+		var report SMSDeliveryReport
+		if err := c.ShouldBind(&report); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var delivery models.SMSDelivery
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := db.ForUpdate(tx).Where("message_id = ?", report.ID).First(&delivery).Error; err != nil {
+				return err
+			}
+
+			if models.IsFinalSMSStatus(delivery.Status) && !models.IsFinalSMSStatus(report.Status) {
+				return nil
+			}
+
+			updates := map[string]interface{}{
+				"status":         report.Status,
+				"network_code":   report.NetworkCode,
+				"failure_reason": report.FailureReason,
+			}
+			if report.Status == "Success" && delivery.DeliveredAt == nil {
+				updates["delivered_at"] = time.Now()
+			}
+
+			return tx.Model(&delivery).Updates(updates).Error
+		})
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			log.Printf("SMS delivery report for unknown message ID %s (status %s)", report.ID, report.Status)
+			c.JSON(http.StatusOK, gin.H{"message": "delivery report ignored: unknown message ID"})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, gin.H{"message": "delivery report received"})
+		}
+	}
+}
+
+// ListSMSDeliveries lets support see whether text messages reached the
+// customer, newest first. Filter with ?order_id=, ?phone_number= or
+// ?status= (the provider's status, e.g. Success or Failed).
+func ListSMSDeliveries(c *gin.Context) {
+	limit, err := parsePageLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	const sort = "-created"
+	cursor, err := decodeCursor(c.Query("cursor"), sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.DB.Model(&models.SMSDelivery{})
+
+	if orderIDParam := c.Query("order_id"); orderIDParam != "" {
+		var orderID uint
+		if _, err := fmt.Sscan(orderIDParam, &orderID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order_id"})
+			return
+		}
+		query = query.Where("sms_deliveries.order_id = ?", orderID)
+	}
+	if phoneNumber := c.Query("phone_number"); phoneNumber != "" {
+		query = query.Where("sms_deliveries.phone_number = ?", phoneNumber)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("sms_deliveries.status = ?", status)
+	}
+
+	query = applyKeyset(query, "sms_deliveries", "id", true, cursor)
+
+	var deliveries []models.SMSDelivery
+	if err := query.Limit(limit + 1).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var nextCursor *string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		encoded := encodeCursor(pageCursor{Sort: sort, ID: deliveries[limit-1].ID})
+		nextCursor = &encoded
+	}
+
+	if deliveries == nil {
+		deliveries = []models.SMSDelivery{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "next_cursor": nextCursor})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+type CreateStaffUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateStaffRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// errLastAdmin stops a change that would leave no one able to grant roles.
+var errLastAdmin = errors.New("cannot remove the last admin")
+
+// ListStaffUsers lists staff users and their roles.
+func ListStaffUsers(c *gin.Context) {
+	query := db.DB.Order("email")
+	if role := c.Query("role"); role != "" {
+		if !models.IsValidRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown role: %s", role)})
+			return
+		}
+		query = query.Where("role = ?", role)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "roles": models.Roles})
+}
+
+// CreateStaffUser grants a role to a new staff member. They sign in through
+// the identity provider with the same, verified, email.
+func CreateStaffUser(c *gin.Context) {
+	var req CreateStaffUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown role: %s", req.Role)})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = email
+	}
+
+	user := models.User{Name: name, Email: email, Role: req.Role}
+	var conflict gin.H
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.User{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			conflict = gin.H{"error": fmt.Sprintf("a staff user with email %s already exists", email)}
+			return nil
+		}
+		return tx.Create(&user).Error
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if conflict != nil {
+		c.JSON(http.StatusConflict, conflict)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateStaffRole changes a staff user's role. The change applies to their
+// next request.
+func UpdateStaffRole(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateStaffRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown role: %s", req.Role)})
+		return
+	}
+
+	var user models.User
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := db.ForUpdate(tx).First(&user, id).Error; err != nil {
+			return err
+		}
+
+		if user.Role == models.RoleAdmin && req.Role != models.RoleAdmin {
+			if err := ensureAnotherAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		// A role set here is the admin's call, so IdP group mappings stop
+		// managing the user.
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"role":        req.Role,
+			"role_source": models.RoleSourceManual,
+		}).Error
+	})
+
+	if err != nil {
+		respondStaffError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteStaffUser removes a staff user and ends their sessions.
+func DeleteStaffUser(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := db.ForUpdate(tx).First(&user, id).Error; err != nil {
+			return err
+		}
+
+		if user.Role == models.RoleAdmin {
+			if err := ensureAnotherAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+
+	if err != nil {
+		respondStaffError(c, id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "staff user removed"})
+}
+
+// ensureAnotherAdmin returns errLastAdmin unless an admin other than
+// userID exists.
+func ensureAnotherAdmin(tx *gorm.DB, userID uint) error {
+	var admins int64
+	if err := tx.Model(&models.User{}).Where("role = ? AND id <> ?", models.RoleAdmin, userID).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins == 0 {
+		return errLastAdmin
+	}
+	return nil
+}
+
+func respondStaffError(c *gin.Context, id uint, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Staff user not found with ID: %d", id)})
+	case errors.Is(err, errLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
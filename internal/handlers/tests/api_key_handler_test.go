@@ -0,0 +1,132 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupAPIKeyTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.User) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.User{}, &models.APIKey{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	admin := &models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin}
+	testDB.Create(admin)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", admin)
+		c.Next()
+	})
+	r.GET("/api/admin/api-keys", handlers.ListAPIKeys)
+	r.POST("/api/admin/api-keys", handlers.CreateAPIKey)
+	r.DELETE("/api/admin/api-keys/:id", handlers.RevokeAPIKey)
+	r.POST("/api/admin/api-keys/:id/rotate", handlers.RotateAPIKey)
+
+	return r, testDB, admin
+}
+
+func TestAPIKeyAdmin(t *testing.T) {
+	router, testDB, admin := setupAPIKeyTestRouter(t)
+
+	var created handlers.APIKeyResponse
+
+	t.Run("Creates a key and shows it only once", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPost, "/api/admin/api-keys", gin.H{
+			"name":   "warehouse",
+			"scopes": []string{models.ScopeOrdersRead, models.ScopeCatalogWrite, models.ScopeOrdersRead},
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.Equal(t, []string{models.ScopeCatalogWrite, models.ScopeOrdersRead}, created.Scopes)
+		assert.True(t, created.Active)
+		assert.Equal(t, admin.ID, *created.CreatedByID)
+
+		prefix, ok := models.ParseAPIKeyPrefix(created.Key)
+		assert.True(t, ok)
+		assert.Equal(t, created.Prefix, prefix)
+
+		var stored models.APIKey
+		testDB.First(&stored, created.ID)
+		assert.Equal(t, models.HashAPIKey(created.Key), stored.KeyHash)
+
+		w = performStaffRequest(router, http.MethodGet, "/api/admin/api-keys", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), created.Key)
+		assert.NotContains(t, w.Body.String(), stored.KeyHash)
+	})
+
+	t.Run("Rejects unknown scopes and past expiry", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPost, "/api/admin/api-keys", gin.H{"name": "bad", "scopes": []string{"everything"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performStaffRequest(router, http.MethodPost, "/api/admin/api-keys", gin.H{
+			"name":       "bad",
+			"scopes":     []string{models.ScopeOrdersRead},
+			"expires_at": time.Now().Add(-time.Hour),
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Rotates a key, keeping the old one for the grace period", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPost, fmt.Sprintf("/api/admin/api-keys/%d/rotate", created.ID), gin.H{"grace_period": "1h"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			APIKey   handlers.APIKeyResponse `json:"api_key"`
+			Previous handlers.APIKeyResponse `json:"previous"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.NotEmpty(t, body.APIKey.Key)
+		assert.NotEqual(t, created.Prefix, body.APIKey.Prefix)
+		assert.Equal(t, created.Scopes, body.APIKey.Scopes)
+		assert.Equal(t, body.APIKey.ID, *body.Previous.RotatedToID)
+		assert.True(t, body.Previous.Active)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *body.Previous.ExpiresAt, time.Minute)
+
+		w = performStaffRequest(router, http.MethodPost, fmt.Sprintf("/api/admin/api-keys/%d/rotate", body.APIKey.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var rotated models.APIKey
+		testDB.First(&rotated, body.APIKey.ID)
+		assert.NotNil(t, rotated.RevokedAt)
+	})
+
+	t.Run("Revokes a key", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodDelete, fmt.Sprintf("/api/admin/api-keys/%d", created.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stored models.APIKey
+		testDB.First(&stored, created.ID)
+		assert.NotNil(t, stored.RevokedAt)
+
+		w = performStaffRequest(router, http.MethodPost, fmt.Sprintf("/api/admin/api-keys/%d/rotate", created.ID), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performStaffRequest(router, http.MethodDelete, "/api/admin/api-keys/9999", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -29,11 +29,13 @@ func setupCategoryTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 		panic("failed to connect test database: " + err.Error())
 	}
 
-	err = testDB.AutoMigrate(&models.Category{})
+	err = testDB.AutoMigrate(&models.Category{}, &models.Product{}, &models.OrderItem{})
 	if err != nil {
 		panic("failed to auto-migrate models: " + err.Error())
 	}
 
+    testDB.Exec("DELETE FROM order_items;")
+    testDB.Exec("DELETE FROM products;")
     testDB.Exec("DELETE FROM categories;")
 
 	originalDB := db.DB // Store the original DB instance before setting test DB
@@ -48,6 +50,13 @@ func setupCategoryTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 	api := r.Group("/api")
 	{
 		api.POST("/categories", handlers.CreateCategory)
+		api.GET("/categories", handlers.ListCategories)
+		api.GET("/categories/tree", handlers.GetCategoryTree)
+		api.GET("/categories/:id", handlers.GetCategory)
+		api.GET("/categories/:id/breadcrumbs", handlers.GetCategoryBreadcrumbs)
+		api.PUT("/categories/:id", handlers.UpdateCategory)
+		api.PATCH("/categories/:id", handlers.PatchCategory)
+		api.DELETE("/categories/:id", handlers.DeleteCategory)
 	}
 
 	// This t.Cleanup now correctly refers to the t passed into the function
@@ -176,4 +185,305 @@ func TestCreateCategoryHandler(t *testing.T) {
 		// In a service layer test, you would mock the service's DB dependency.
 		t.Skip("Skipping direct database error simulation in handler test for simplicity.")
 	})
-}
\ No newline at end of file
+}
+
+func TestGetCategoryHandler(t *testing.T) {
+	router, testDB := setupCategoryTestRouter(t)
+
+	root := models.Category{Name: "Home"}
+	testDB.Create(&root)
+	child := models.Category{Name: "Kitchen", ParentID: &root.ID}
+	testDB.Create(&child)
+
+	t.Run("Returns a category with its parent and children", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/categories/%d", root.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var responseCategory models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseCategory))
+		assert.Equal(t, "Home", responseCategory.Name)
+		assert.Len(t, responseCategory.Children, 1)
+		assert.Equal(t, "Kitchen", responseCategory.Children[0].Name)
+	})
+
+	t.Run("Lists only root categories", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories?parent_id=root", nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var categories []models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &categories))
+		assert.Len(t, categories, 1)
+		assert.Equal(t, root.ID, categories[0].ID)
+	})
+
+	t.Run("Returns 404 for unknown category", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/999", nil, 1)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Returns 400 for non-numeric id", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/abc", nil, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestUpdateCategoryHandler(t *testing.T) {
+	router, testDB := setupCategoryTestRouter(t)
+
+	// Electronics -> Computers -> Laptops, and a separate Books root
+	electronics := models.Category{Name: "Electronics"}
+	testDB.Create(&electronics)
+	computers := models.Category{Name: "Computers", ParentID: &electronics.ID}
+	testDB.Create(&computers)
+	laptops := models.Category{Name: "Laptops", ParentID: &computers.ID}
+	testDB.Create(&laptops)
+	books := models.Category{Name: "Books"}
+	testDB.Create(&books)
+
+	t.Run("PUT renames and reparents a category", func(t *testing.T) {
+		reqBody := handlers.UpdateCategoryRequest{Name: "Notebooks", ParentID: &electronics.ID}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", laptops.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var responseCategory models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseCategory))
+		assert.Equal(t, "Notebooks", responseCategory.Name)
+		assert.Equal(t, electronics.ID, *responseCategory.ParentID)
+		assert.Equal(t, "Electronics", responseCategory.Parent.Name)
+	})
+
+	t.Run("PATCH with explicit null parent makes the category a root", func(t *testing.T) {
+		reqBody := map[string]interface{}{"parent_id": nil}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/categories/%d", laptops.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var stored models.Category
+		testDB.First(&stored, laptops.ID)
+		assert.Nil(t, stored.ParentID)
+		assert.Equal(t, "Notebooks", stored.Name)
+	})
+
+	t.Run("PATCH without parent_id leaves the parent untouched", func(t *testing.T) {
+		reqBody := map[string]interface{}{"name": "Desktop Computers"}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/categories/%d", computers.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var stored models.Category
+		testDB.First(&stored, computers.ID)
+		assert.Equal(t, "Desktop Computers", stored.Name)
+		assert.Equal(t, electronics.ID, *stored.ParentID)
+	})
+
+	t.Run("Rejects making a category its own parent", func(t *testing.T) {
+		reqBody := map[string]interface{}{"parent_id": books.ID}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/categories/%d", books.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response map[string]string
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, "Category cannot be its own parent", response["error"])
+	})
+
+	t.Run("Rejects moving a category under one of its descendants", func(t *testing.T) {
+		reqBody := map[string]interface{}{"parent_id": computers.ID}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/categories/%d", electronics.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		var stored models.Category
+		testDB.First(&stored, electronics.ID)
+		assert.Nil(t, stored.ParentID)
+	})
+
+	t.Run("Returns 404 when the new parent does not exist", func(t *testing.T) {
+		reqBody := map[string]interface{}{"parent_id": 999}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/categories/%d", books.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("PUT requires a name", func(t *testing.T) {
+		reqBody := map[string]interface{}{"parent_id": nil}
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", books.ID), reqBody, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestDeleteCategoryHandler(t *testing.T) {
+	router, testDB := setupCategoryTestRouter(t)
+
+	t.Run("Deletes an empty category with the default strategy", func(t *testing.T) {
+		empty := models.Category{Name: "Empty"}
+		testDB.Create(&empty)
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d", empty.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var count int64
+		testDB.Model(&models.Category{}).Where("id = ?", empty.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Rejects deleting a category with children by default", func(t *testing.T) {
+		parent := models.Category{Name: "Garden"}
+		testDB.Create(&parent)
+		testDB.Create(&models.Category{Name: "Tools", ParentID: &parent.ID})
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d", parent.ID), nil, 1)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		var response map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, float64(1), response["children"])
+	})
+
+	t.Run("Cascade removes the whole subtree and its products", func(t *testing.T) {
+		parent := models.Category{Name: "Toys"}
+		testDB.Create(&parent)
+		child := models.Category{Name: "Puzzles", ParentID: &parent.ID}
+		testDB.Create(&child)
+		testDB.Create(&models.Product{Name: "Jigsaw", Price: models.NewMoney(1500, "KES"), CategoryID: child.ID})
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d?strategy=cascade", parent.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var categoryCount, productCount int64
+		testDB.Model(&models.Category{}).Where("id IN ?", []uint{parent.ID, child.ID}).Count(&categoryCount)
+		testDB.Model(&models.Product{}).Where("category_id = ?", child.ID).Count(&productCount)
+		assert.Equal(t, int64(0), categoryCount)
+		assert.Equal(t, int64(0), productCount)
+	})
+
+	t.Run("Cascade refuses when products in the subtree have been ordered", func(t *testing.T) {
+		parent := models.Category{Name: "Music"}
+		testDB.Create(&parent)
+		product := models.Product{Name: "Guitar", Price: models.NewMoney(25000, "KES"), CategoryID: parent.ID}
+		testDB.Create(&product)
+		testDB.Create(&models.OrderItem{OrderID: 1, ProductID: product.ID, Quantity: 1, Price: product.Price})
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d?strategy=cascade", parent.ID), nil, 1)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("Reassign moves children and products to the parent", func(t *testing.T) {
+		grandparent := models.Category{Name: "Sports"}
+		testDB.Create(&grandparent)
+		parent := models.Category{Name: "Ball Games", ParentID: &grandparent.ID}
+		testDB.Create(&parent)
+		child := models.Category{Name: "Football", ParentID: &parent.ID}
+		testDB.Create(&child)
+		product := models.Product{Name: "Whistle", Price: models.NewMoney(500, "KES"), CategoryID: parent.ID}
+		testDB.Create(&product)
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d?strategy=reassign", parent.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var storedChild models.Category
+		testDB.First(&storedChild, child.ID)
+		assert.Equal(t, grandparent.ID, *storedChild.ParentID)
+
+		var storedProduct models.Product
+		testDB.First(&storedProduct, product.ID)
+		assert.Equal(t, grandparent.ID, storedProduct.CategoryID)
+	})
+
+	t.Run("Reassign refuses to orphan products of a root category", func(t *testing.T) {
+		root := models.Category{Name: "Outdoors"}
+		testDB.Create(&root)
+		testDB.Create(&models.Product{Name: "Tent", Price: models.NewMoney(12000, "KES"), CategoryID: root.ID})
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d?strategy=reassign", root.ID), nil, 1)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("Returns 400 for an unknown strategy", func(t *testing.T) {
+		category := models.Category{Name: "Misc"}
+		testDB.Create(&category)
+
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d?strategy=shred", category.ID), nil, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestCategoryTreeHandlers(t *testing.T) {
+	router, testDB := setupCategoryTestRouter(t)
+
+	// Electronics -> Computers -> Laptops, Electronics -> Phones, and Books
+	electronics := models.Category{Name: "Electronics"}
+	testDB.Create(&electronics)
+	computers := models.Category{Name: "Computers", ParentID: &electronics.ID}
+	testDB.Create(&computers)
+	laptops := models.Category{Name: "Laptops", ParentID: &computers.ID}
+	testDB.Create(&laptops)
+	phones := models.Category{Name: "Phones", ParentID: &electronics.ID}
+	testDB.Create(&phones)
+	books := models.Category{Name: "Books"}
+	testDB.Create(&books)
+
+	t.Run("Returns the whole hierarchy as nested JSON", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/tree", nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var tree []models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tree))
+		assert.Len(t, tree, 2)
+		assert.Equal(t, "Electronics", tree[0].Name)
+		assert.Len(t, tree[0].Children, 2)
+		assert.Equal(t, "Computers", tree[0].Children[0].Name)
+		assert.Equal(t, "Laptops", tree[0].Children[0].Children[0].Name)
+		assert.Equal(t, "Books", tree[1].Name)
+		assert.Empty(t, tree[1].Children)
+	})
+
+	t.Run("Returns a subtree when root_id is given", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/categories/tree?root_id=%d", computers.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var tree []models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tree))
+		assert.Len(t, tree, 1)
+		assert.Equal(t, "Computers", tree[0].Name)
+		assert.Len(t, tree[0].Children, 1)
+	})
+
+	t.Run("Truncates the tree at max_depth", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/tree?max_depth=2", nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var tree []models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tree))
+		assert.Len(t, tree[0].Children, 2)
+		assert.Empty(t, tree[0].Children[0].Children)
+	})
+
+	t.Run("Returns 400 for an invalid max_depth", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/tree?max_depth=0", nil, 1)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Returns breadcrumbs from the root down", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/categories/%d/breadcrumbs", laptops.ID), nil, 1)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var path []models.Category
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &path))
+		assert.Len(t, path, 3)
+		assert.Equal(t, "Electronics", path[0].Name)
+		assert.Equal(t, "Computers", path[1].Name)
+		assert.Equal(t, "Laptops", path[2].Name)
+	})
+
+	t.Run("Returns 404 breadcrumbs for unknown category", func(t *testing.T) {
+		recorder := performCategoryAuthenticatedRequest(router, http.MethodGet, "/api/categories/999/breadcrumbs", nil, 1)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
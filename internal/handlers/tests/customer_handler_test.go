@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupCustomerTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.Customer) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := testDB.AutoMigrate(&models.Customer{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+	testDB.Exec("DELETE FROM customers;")
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	customer := &models.Customer{Name: "Jane", Email: "jane@example.com", Phone: "+254712345678"}
+	testDB.Create(customer)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("customer", customer)
+		c.Next()
+	})
+	r.GET("/api/me", handlers.GetProfile)
+	r.PATCH("/api/me", handlers.UpdateProfile)
+
+	return r, testDB, customer
+}
+
+func patchProfile(router *gin.Engine, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/api/me", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetProfile(t *testing.T) {
+	router, _, customer := setupCustomerTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/me", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body models.Customer
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, customer.ID, body.ID)
+	assert.Equal(t, "jane@example.com", body.Email)
+	assert.NotContains(t, w.Body.String(), "NotificationPreferences")
+}
+
+func TestUpdateProfile(t *testing.T) {
+	router, testDB, customer := setupCustomerTestRouter(t)
+
+	t.Run("Normalizes the phone number to E.164", func(t *testing.T) {
+		w := patchProfile(router, gin.H{"phone": "0722 000 111"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body models.Customer
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Equal(t, "+254722000111", body.Phone)
+
+		var reloaded models.Customer
+		testDB.First(&reloaded, customer.ID)
+		assert.Equal(t, "+254722000111", reloaded.Phone)
+		assert.Equal(t, "Jane", reloaded.Name)
+	})
+
+	t.Run("Uses the configured default country", func(t *testing.T) {
+		handlers.SetDefaultPhoneCountry("UG")
+		t.Cleanup(func() { handlers.SetDefaultPhoneCountry(models.DefaultPhoneCountry) })
+
+		w := patchProfile(router, gin.H{"phone": "0772 123456"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.Customer
+		testDB.First(&reloaded, customer.ID)
+		assert.Equal(t, "+256772123456", reloaded.Phone)
+	})
+
+	t.Run("Rejects an invalid phone number", func(t *testing.T) {
+		w := patchProfile(router, gin.H{"phone": "0722 000"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid phone number")
+	})
+
+	t.Run("Updates name and locale", func(t *testing.T) {
+		w := patchProfile(router, gin.H{"name": "Jane Wanjiru", "locale": "sw-KE"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.Customer
+		testDB.First(&reloaded, customer.ID)
+		assert.Equal(t, "Jane Wanjiru", reloaded.Name)
+		assert.Equal(t, "sw-KE", reloaded.Locale)
+	})
+
+	t.Run("Rejects an empty name", func(t *testing.T) {
+		w := patchProfile(router, gin.H{"name": "  "})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
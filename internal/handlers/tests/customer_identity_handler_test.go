@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupIdentityTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.Customer) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.Customer{}, &models.CustomerIdentity{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	customer := &models.Customer{Name: "Jane", Email: "jane@example.com"}
+	testDB.Create(customer)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("customer", customer)
+		c.Next()
+	})
+	r.GET("/api/me/identities", handlers.ListIdentities)
+	r.DELETE("/api/me/identities/:id", handlers.UnlinkIdentity)
+
+	return r, testDB, customer
+}
+
+func TestCustomerIdentities(t *testing.T) {
+	router, testDB, jane := setupIdentityTestRouter(t)
+
+	other := models.Customer{Name: "Other", Email: "other@example.com"}
+	testDB.Create(&other)
+
+	google := models.CustomerIdentity{CustomerID: jane.ID, Provider: "google", Issuer: "https://accounts.google.com", Subject: "1", Email: "jane@gmail.com"}
+	keycloak := models.CustomerIdentity{CustomerID: jane.ID, Provider: "keycloak", Issuer: "https://sso.example.com", Subject: "1"}
+	theirs := models.CustomerIdentity{CustomerID: other.ID, Provider: "google", Issuer: "https://accounts.google.com", Subject: "2"}
+	testDB.Create(&google)
+	testDB.Create(&keycloak)
+	testDB.Create(&theirs)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Lists the customer's own logins", func(t *testing.T) {
+		w := request(http.MethodGet, "/api/me/identities")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			Identities []handlers.IdentityResponse `json:"identities"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if assert.Len(t, body.Identities, 2) {
+			assert.Equal(t, "google", body.Identities[0].Provider)
+			assert.Equal(t, "jane@gmail.com", body.Identities[0].Email)
+			assert.Equal(t, "keycloak", body.Identities[1].Provider)
+		}
+		assert.NotContains(t, w.Body.String(), "subject")
+	})
+
+	t.Run("Does not unlink another customer's login", func(t *testing.T) {
+		w := request(http.MethodDelete, fmt.Sprintf("/api/me/identities/%d", theirs.ID))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Unlinks all but the last login", func(t *testing.T) {
+		w := request(http.MethodDelete, fmt.Sprintf("/api/me/identities/%d", google.ID))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = request(http.MethodDelete, fmt.Sprintf("/api/me/identities/%d", keycloak.ID))
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var remaining int64
+		testDB.Model(&models.CustomerIdentity{}).Where("customer_id = ?", jane.ID).Count(&remaining)
+		assert.Equal(t, int64(1), remaining)
+	})
+}
@@ -0,0 +1,185 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func performIdempotentOrderRequest(router *gin.Engine, rawBody string, key string, customerID uint) *httptest.ResponseRecorder {
+	return performIdempotentRequest(router, "/api/orders", rawBody, key, customerID)
+}
+
+func performIdempotentRequest(router *gin.Engine, path, rawBody string, key string, customerID uint) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(rawBody))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(handlers.IdempotencyKeyHeader, key)
+	}
+
+	tempW := httptest.NewRecorder()
+	tempC, _ := gin.CreateTestContext(tempW)
+	tempC.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	store := cookie.NewStore([]byte("test-secret-key"))
+	sessions.Sessions("gosess", store)(tempC)
+	session := sessions.Default(tempC)
+	session.Set("customer_id", customerID)
+	session.Save()
+	req.Header.Set("Cookie", tempW.Header().Get("Set-Cookie"))
+
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotentCreateOrder(t *testing.T) {
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Phones"}
+	testDB.Create(&category)
+
+	customer := models.Customer{Name: "Flaky Network", Email: "flaky@example.com", Phone: "0755555555"}
+	other := models.Customer{Name: "Someone Else", Email: "else@example.com", Phone: "0766666666"}
+	testDB.Create(&customer)
+	testDB.Create(&other)
+
+	phone := models.Product{Name: "Feature Phone", Price: models.NewMoney(200000, "KES"), Stock: 100, CategoryID: category.ID}
+	testDB.Create(&phone)
+
+	body := `{"items": [{"product_id": ` + jsonUint(phone.ID) + `, "quantity": 1}]}`
+
+	countOrders := func() int64 {
+		var count int64
+		testDB.Model(&models.Order{}).Count(&count)
+		return count
+	}
+
+	t.Run("Replays the original response for a retried key", func(t *testing.T) {
+		before := countOrders()
+
+		first := performIdempotentOrderRequest(router, body, "retry-1", customer.ID)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		// Same content, different formatting and key order.
+		reformatted := `{ "items":[ {"quantity":1, "product_id":` + jsonUint(phone.ID) + `} ] }`
+		second := performIdempotentOrderRequest(router, reformatted, "retry-1", customer.ID)
+
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.Equal(t, "true", second.Header().Get(handlers.IdempotentReplayedHeader))
+		assert.JSONEq(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, before+1, countOrders())
+	})
+
+	t.Run("Returns 422 when a key is reused with a different body", func(t *testing.T) {
+		performIdempotentOrderRequest(router, body, "retry-2", customer.ID)
+
+		different := `{"items": [{"product_id": ` + jsonUint(phone.ID) + `, "quantity": 2}]}`
+		recorder := performIdempotentOrderRequest(router, different, "retry-2", customer.ID)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("Keys are scoped per customer", func(t *testing.T) {
+		before := countOrders()
+
+		performIdempotentOrderRequest(router, body, "shared-key", customer.ID)
+		recorder := performIdempotentOrderRequest(router, body, "shared-key", other.ID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(handlers.IdempotentReplayedHeader))
+		assert.Equal(t, before+2, countOrders())
+	})
+
+	t.Run("Expired keys create a new order", func(t *testing.T) {
+		performIdempotentOrderRequest(router, body, "old-key", customer.ID)
+		testDB.Model(&models.IdempotencyKey{}).Where("key = ?", "old-key").Update("expires_at", time.Now().Add(-time.Minute))
+		before := countOrders()
+
+		recorder := performIdempotentOrderRequest(router, body, "old-key", customer.ID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(handlers.IdempotentReplayedHeader))
+		assert.Equal(t, before+1, countOrders())
+	})
+
+	t.Run("Reports a request that is still in flight", func(t *testing.T) {
+		locked := time.Now().Add(time.Minute)
+		testDB.Create(&models.IdempotencyKey{
+			CustomerID:  customer.ID,
+			Key:         "in-flight",
+			RequestHash: canonicalHash(body),
+			LockedUntil: &locked,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+
+		recorder := performIdempotentOrderRequest(router, body, "in-flight", customer.ID)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("Takes over a claim whose lock ran out", func(t *testing.T) {
+		expired := time.Now().Add(-time.Second)
+		testDB.Create(&models.IdempotencyKey{
+			CustomerID:  customer.ID,
+			Key:         "crashed",
+			RequestHash: canonicalHash(body),
+			LockedUntil: &expired,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+		before := countOrders()
+
+		recorder := performIdempotentOrderRequest(router, body, "crashed", customer.ID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, before+1, countOrders())
+	})
+
+	t.Run("Releases the key when the handler panics", func(t *testing.T) {
+		router.POST("/api/explode", handlers.Idempotency(), func(c *gin.Context) { panic("boom") })
+
+		for i := 0; i < 2; i++ {
+			recorder := performIdempotentRequest(router, "/api/explode", body, "explode", customer.ID)
+			assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		}
+
+		var count int64
+		testDB.Model(&models.IdempotencyKey{}).Where("key = ?", "explode").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Requests without a key are not deduplicated", func(t *testing.T) {
+		before := countOrders()
+
+		performIdempotentOrderRequest(router, body, "", customer.ID)
+		performIdempotentOrderRequest(router, body, "", customer.ID)
+
+		assert.Equal(t, before+2, countOrders())
+	})
+}
+
+func jsonUint(v uint) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// canonicalHash mirrors how the middleware fingerprints a JSON body.
+func canonicalHash(body string) string {
+	var decoded interface{}
+	json.Unmarshal([]byte(body), &decoded)
+	canonical, _ := json.Marshal(decoded)
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
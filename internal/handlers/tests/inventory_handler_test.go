@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func TestStockHandlers(t *testing.T) {
+	router, testDB := setupProductTestRouter(t)
+
+	category := models.Category{Name: "Stationery"}
+	testDB.Create(&category)
+	product := models.Product{Name: "Notebook", Price: models.NewMoney(15000, "KES"), CategoryID: category.ID}
+	testDB.Create(&product)
+
+	t.Run("Restock adds to the stock and writes a ledger entry", func(t *testing.T) {
+		reqBody := handlers.RestockRequest{Quantity: 40, Note: "PO-1182"}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, fmt.Sprintf("/api/products/%d/stock/restock", product.ID), reqBody))
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		var movement models.InventoryMovement
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &movement))
+		assert.Equal(t, 40, movement.Change)
+		assert.Equal(t, 40, movement.BalanceAfter)
+		assert.Equal(t, models.InventoryReasonRestock, movement.Reason)
+		assert.Equal(t, "PO-1182", movement.Note)
+
+		var stored models.Product
+		testDB.First(&stored, product.ID)
+		assert.Equal(t, 40, stored.Stock)
+	})
+
+	t.Run("Restock rejects a non-positive quantity", func(t *testing.T) {
+		reqBody := map[string]interface{}{"quantity": -5}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, fmt.Sprintf("/api/products/%d/stock/restock", product.ID), reqBody))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Adjustment applies a signed change", func(t *testing.T) {
+		reqBody := handlers.StockAdjustmentRequest{Change: -3, Note: "water damage"}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, fmt.Sprintf("/api/products/%d/stock/adjustments", product.ID), reqBody))
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		var stored models.Product
+		testDB.First(&stored, product.ID)
+		assert.Equal(t, 37, stored.Stock)
+	})
+
+	t.Run("Adjustment cannot take stock below zero", func(t *testing.T) {
+		reqBody := handlers.StockAdjustmentRequest{Change: -100, Note: "stock take"}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, fmt.Sprintf("/api/products/%d/stock/adjustments", product.ID), reqBody))
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		var stored models.Product
+		testDB.First(&stored, product.ID)
+		assert.Equal(t, 37, stored.Stock)
+	})
+
+	t.Run("Adjustment requires a note", func(t *testing.T) {
+		reqBody := map[string]interface{}{"change": 2}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, fmt.Sprintf("/api/products/%d/stock/adjustments", product.ID), reqBody))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Lists the ledger newest first", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/products/%d/stock/movements", product.ID), nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var response struct {
+			Stock     int                        `json:"stock"`
+			Movements []models.InventoryMovement `json:"movements"`
+		}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, 37, response.Stock)
+		assert.Len(t, response.Movements, 2)
+		assert.Equal(t, -3, response.Movements[0].Change)
+		assert.Equal(t, 40, response.Movements[1].Change)
+	})
+
+	t.Run("Returns 404 for an unknown product", func(t *testing.T) {
+		reqBody := handlers.RestockRequest{Quantity: 1}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, createProductRequest(http.MethodPost, "/api/products/999/stock/restock", reqBody))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,222 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+)
+
+const testUnsubscribeSecret = "unsubscribe-secret"
+
+func setupNotificationPreferencesTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.Customer) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := testDB.AutoMigrate(&models.Customer{}, &models.NotificationPreference{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+	testDB.Exec("DELETE FROM customers;")
+	testDB.Exec("DELETE FROM notification_preferences;")
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	handlers.SetUnsubscribeLinks("https://shop.example.com", testUnsubscribeSecret)
+	t.Cleanup(func() { handlers.SetUnsubscribeLinks("", "") })
+
+	customer := &models.Customer{Name: "Jane", Email: "jane@example.com", Phone: "+254712345678"}
+	testDB.Create(customer)
+
+	r := gin.New()
+	r.GET("/unsubscribe", handlers.ConfirmUnsubscribe)
+	r.POST("/unsubscribe", handlers.Unsubscribe)
+
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set("customer", customer)
+		c.Next()
+	})
+	api.GET("/me/notifications", handlers.GetNotificationPreferences)
+	api.PUT("/me/notifications", handlers.UpdateNotificationPreferences)
+
+	return r, testDB, customer
+}
+
+type preferencesResponse struct {
+	Preferences []struct {
+		Channel string `json:"channel"`
+		Event   string `json:"event"`
+		Enabled bool   `json:"enabled"`
+	} `json:"preferences"`
+}
+
+func (p preferencesResponse) enabled(channel, event string) bool {
+	for _, pref := range p.Preferences {
+		if pref.Channel == channel && pref.Event == event {
+			return pref.Enabled
+		}
+	}
+	panic("missing preference " + channel + "/" + event)
+}
+
+func performPreferencesRequest(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	router, testDB, customer := setupNotificationPreferencesTestRouter(t)
+
+	t.Run("Everything is enabled by default", func(t *testing.T) {
+		w := performPreferencesRequest(router, http.MethodGet, "/api/me/notifications", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body preferencesResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Preferences, len(models.NotificationChannels)*len(models.NotificationEvents))
+		for _, pref := range body.Preferences {
+			assert.True(t, pref.Enabled, pref.Channel+"/"+pref.Event)
+		}
+	})
+
+	t.Run("Turns a notification off and on again", func(t *testing.T) {
+		w := performPreferencesRequest(router, http.MethodPut, "/api/me/notifications", gin.H{
+			"preferences": []gin.H{{"channel": "sms", "event": "order_shipped", "enabled": false}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body preferencesResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.False(t, body.enabled("sms", "order_shipped"))
+		assert.True(t, body.enabled("sms", "order_placed"))
+
+		w = performPreferencesRequest(router, http.MethodPut, "/api/me/notifications", gin.H{
+			"preferences": []gin.H{{"channel": "sms", "event": "order_shipped", "enabled": true}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var count int64
+		testDB.Model(&models.NotificationPreference{}).Where("customer_id = ?", customer.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Rejects unknown channels, events and missing values", func(t *testing.T) {
+		for _, pref := range []gin.H{
+			{"channel": "pigeon", "event": "order_placed", "enabled": false},
+			{"channel": "sms", "event": "birthday", "enabled": false},
+			{"channel": "sms", "event": "order_placed"},
+		} {
+			w := performPreferencesRequest(router, http.MethodPut, "/api/me/notifications", gin.H{"preferences": []gin.H{pref}})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	router, _, customer := setupNotificationPreferencesTestRouter(t)
+
+	signToken := func(claims gin.H) string {
+		if _, ok := claims["iat"]; !ok {
+			claims["iat"] = time.Now().Unix()
+		}
+		token, _ := utils.SignToken([]byte(testUnsubscribeSecret), claims)
+		return token
+	}
+
+	sign := func(claims gin.H) string {
+		return "/unsubscribe?token=" + url.QueryEscape(signToken(claims))
+	}
+
+	currentPreferences := func() preferencesResponse {
+		w := performPreferencesRequest(router, http.MethodGet, "/api/me/notifications", nil)
+		var body preferencesResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body
+	}
+
+	t.Run("Opening the link only asks for confirmation", func(t *testing.T) {
+		w := performPreferencesRequest(router, http.MethodGet, sign(gin.H{"c": customer.ID, "ch": "email", "e": "order_placed"}), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), `<form method="post" action="/unsubscribe">`)
+		assert.Contains(t, w.Body.String(), "order_placed")
+
+		assert.True(t, currentPreferences().enabled("email", "order_placed"))
+	})
+
+	t.Run("Turns off a single event with a one-click POST", func(t *testing.T) {
+		w := performPreferencesRequest(router, http.MethodPost, sign(gin.H{"c": customer.ID, "ch": "email", "e": "order_placed"}), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		prefs := currentPreferences()
+		assert.False(t, prefs.enabled("email", "order_placed"))
+		assert.True(t, prefs.enabled("email", "order_shipped"))
+		assert.True(t, prefs.enabled("sms", "order_placed"))
+	})
+
+	t.Run("Turns off every event on the channel from the confirmation form", func(t *testing.T) {
+		form := url.Values{"token": {signToken(gin.H{"c": customer.ID, "ch": "email"})}}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/unsubscribe", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		prefs := currentPreferences()
+		assert.False(t, prefs.enabled("email", "order_placed"))
+		assert.False(t, prefs.enabled("email", "order_shipped"))
+		assert.True(t, prefs.enabled("sms", "order_shipped"))
+	})
+
+	t.Run("Rejects forged tokens", func(t *testing.T) {
+		forged, _ := utils.SignToken([]byte("wrong-secret"), gin.H{"c": customer.ID, "ch": "sms", "iat": time.Now().Unix()})
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			w := performPreferencesRequest(router, method, "/unsubscribe?token="+url.QueryEscape(forged), nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w = performPreferencesRequest(router, method, "/unsubscribe", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Rejects expired links", func(t *testing.T) {
+		issued := time.Now().Add(-handlers.UnsubscribeLinkTTL - time.Hour).Unix()
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			w := performPreferencesRequest(router, method, sign(gin.H{"c": customer.ID, "ch": "sms", "iat": issued}), nil)
+			assert.Equal(t, http.StatusGone, w.Code)
+		}
+		assert.True(t, currentPreferences().enabled("sms", "order_placed"))
+	})
+
+	t.Run("Returns 404 for an unknown customer", func(t *testing.T) {
+		w := performPreferencesRequest(router, http.MethodPost, sign(gin.H{"c": 999999, "ch": "sms"}), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
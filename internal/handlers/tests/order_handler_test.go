@@ -3,6 +3,7 @@ package handlers_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,7 +30,7 @@ func setupOrderTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 	}
 
 	// AutoMigrate all relevant models
-	err = testDB.AutoMigrate(&models.Customer{}, &models.Product{}, &models.Order{}, &models.OrderItem{})
+	err = testDB.AutoMigrate(&models.Customer{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.InventoryMovement{}, &models.OrderStatusHistory{}, &models.IdempotencyKey{}, &models.OutboxMessage{}, &models.NotificationPreference{})
 	if err != nil {
 		panic("failed to auto-migrate models: " + err.Error())
 	}
@@ -38,6 +39,12 @@ func setupOrderTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
     testDB.Exec("DELETE FROM categories;")
 	testDB.Exec("DELETE FROM orders;")
 	testDB.Exec("DELETE FROM order_items;")
+	testDB.Exec("DELETE FROM customers;")
+	testDB.Exec("DELETE FROM inventory_movements;")
+	testDB.Exec("DELETE FROM order_status_histories;")
+	testDB.Exec("DELETE FROM idempotency_keys;")
+	testDB.Exec("DELETE FROM outbox_messages;")
+	testDB.Exec("DELETE FROM notification_preferences;")
 
 	originalDB := db.DB
 	db.SetTestDB(testDB)
@@ -50,7 +57,11 @@ func setupOrderTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 
 	api := r.Group("/api")
 	{
-		api.POST("/orders", handlers.CreateOrder)
+		api.POST("/orders", handlers.Idempotency(), handlers.CreateOrder)
+		api.GET("/orders", handlers.ListOrders)
+		api.GET("/orders/:id", handlers.GetOrder)
+		api.PATCH("/orders/:id/status", handlers.UpdateOrderStatus)
+		api.GET("/admin/orders/:id", handlers.GetAnyOrder)
 	}
 
 	t.Cleanup(func() {
@@ -108,8 +119,8 @@ func TestCreateOrderHandler(t *testing.T) {
 	customer := models.Customer{Name: "Test Customer", Email: "test@example.com", Phone: "1234567890"}
 	testDB.Create(&customer)
 
-	product1 := models.Product{Name: "Product A", Price: 10.00, CategoryID: category.ID}
-	product2 := models.Product{Name: "Product B", Price: 20.00, CategoryID: category.ID}
+	product1 := models.Product{Name: "Product A", Price: models.NewMoney(1000, "KES"), Stock: 100, CategoryID: category.ID}
+	product2 := models.Product{Name: "Product B", Price: models.NewMoney(2000, "KES"), Stock: 100, CategoryID: category.ID}
 	testDB.Create(&product1)
 	testDB.Create(&product2)
 
@@ -165,7 +176,7 @@ func TestCreateOrderHandler(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 		var response map[string]string
 		json.Unmarshal(recorder.Body.Bytes(), &response)
-		assert.Equal(t, "product_ids required", response["error"])
+		assert.Equal(t, "items or product_ids required", response["error"])
 	})
 
 	t.Run("Returns 400 for empty product_ids", func(t *testing.T) {
@@ -178,7 +189,7 @@ func TestCreateOrderHandler(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
 		var response map[string]string
 		json.Unmarshal(recorder.Body.Bytes(), &response)
-		assert.Equal(t, "product_ids required", response["error"])
+		assert.Equal(t, "items or product_ids required", response["error"])
 	})
 
 	t.Run("Returns 400 if customer not found", func(t *testing.T) {
@@ -224,4 +235,530 @@ func TestCreateOrderHandler(t *testing.T) {
 		// However, for this direct handler test, we'll acknowledge the complexity.
 		t.Skip("Skipping direct database error simulation at handler level for simplicity.")
 	})
-}
\ No newline at end of file
+}
+
+func TestCreateOrderWithItemsHandler(t *testing.T) {
+
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Groceries"}
+	testDB.Create(&category)
+
+	customer := models.Customer{Name: "Bulk Buyer", Email: "bulk@example.com", Phone: "0700000000"}
+	testDB.Create(&customer)
+
+	rice := models.Product{Name: "Rice 2kg", Price: models.NewMoney(25050, "KES"), Stock: 100, CategoryID: category.ID}
+	oil := models.Product{Name: "Cooking Oil", Price: models.NewMoney(39900, "KES"), Stock: 100, CategoryID: category.ID}
+	testDB.Create(&rice)
+	testDB.Create(&oil)
+
+	custID := customer.ID
+
+	t.Run("Creates line items with the requested quantities", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{
+				{ProductID: rice.ID, Quantity: 5},
+				{ProductID: oil.ID, Quantity: 2},
+			},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+
+		var response struct {
+			Order models.Order `json:"order"`
+		}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Len(t, response.Order.Items, 2)
+		assert.Equal(t, uint(5), response.Order.Items[0].Quantity)
+		assert.Equal(t, rice.Price, response.Order.Items[0].Price)
+		assert.Equal(t, uint(2), response.Order.Items[1].Quantity)
+	})
+
+	t.Run("Merges duplicate products across items and product_ids", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{
+				{ProductID: rice.ID, Quantity: 2},
+				{ProductID: rice.ID, Quantity: 3},
+			},
+			ProductIDs: []uint{oil.ID, rice.ID},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+
+		var response struct {
+			Order models.Order `json:"order"`
+		}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Len(t, response.Order.Items, 2)
+		assert.Equal(t, rice.ID, response.Order.Items[0].ProductID)
+		assert.Equal(t, uint(6), response.Order.Items[0].Quantity)
+		assert.Equal(t, oil.ID, response.Order.Items[1].ProductID)
+		assert.Equal(t, uint(1), response.Order.Items[1].Quantity)
+	})
+
+	t.Run("Returns 400 for a zero quantity", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: rice.ID, Quantity: 0}},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response map[string]string
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Contains(t, response["error"], "must be between 1 and 1000")
+	})
+
+	t.Run("Returns 400 when merged quantities exceed the limit", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{
+				{ProductID: rice.ID, Quantity: 600},
+				{ProductID: rice.ID, Quantity: 600},
+			},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Returns 400 for an item without a product_id", func(t *testing.T) {
+		reqBody := map[string]interface{}{"items": []map[string]interface{}{{"quantity": 1}}}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response map[string]string
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, "product_id is required for every item", response["error"])
+	})
+}
+
+func TestCreateOrderStockHandler(t *testing.T) {
+
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Hardware"}
+	testDB.Create(&category)
+
+	customer := models.Customer{Name: "Stock Checker", Email: "stock@example.com", Phone: "0711111111"}
+	testDB.Create(&customer)
+
+	hammer := models.Product{Name: "Hammer", Price: models.NewMoney(80000, "KES"), Stock: 3, CategoryID: category.ID}
+	nails := models.Product{Name: "Nails", Price: models.NewMoney(5000, "KES"), Stock: 10, CategoryID: category.ID}
+	saw := models.Product{Name: "Saw", Price: models.NewMoney(120000, "KES"), Stock: 0, CategoryID: category.ID}
+	testDB.Create(&hammer)
+	testDB.Create(&nails)
+	testDB.Create(&saw)
+
+	custID := customer.ID
+
+	t.Run("Decrements stock and records the movement", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hammer.ID, Quantity: 2}},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+
+		var stored models.Product
+		testDB.First(&stored, hammer.ID)
+		assert.Equal(t, 1, stored.Stock)
+
+		var movement models.InventoryMovement
+		testDB.Where("product_id = ?", hammer.ID).Last(&movement)
+		assert.Equal(t, -2, movement.Change)
+		assert.Equal(t, 1, movement.BalanceAfter)
+		assert.Equal(t, models.InventoryReasonOrder, movement.Reason)
+		assert.NotNil(t, movement.OrderID)
+	})
+
+	t.Run("Rejects an oversell with 409 listing every short item", func(t *testing.T) {
+		var ordersBefore int64
+		testDB.Model(&models.Order{}).Count(&ordersBefore)
+
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{
+				{ProductID: hammer.ID, Quantity: 2},
+				{ProductID: nails.ID, Quantity: 5},
+				{ProductID: saw.ID, Quantity: 1},
+			},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+
+		var response struct {
+			Error string                   `json:"error"`
+			Items []handlers.StockShortage `json:"items"`
+		}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "insufficient stock", response.Error)
+		assert.Len(t, response.Items, 2)
+		assert.Equal(t, hammer.ID, response.Items[0].ProductID)
+		assert.Equal(t, uint(2), response.Items[0].Requested)
+		assert.Equal(t, 1, response.Items[0].Available)
+		assert.Equal(t, saw.ID, response.Items[1].ProductID)
+
+		// Nothing is reserved when any line is short.
+		var storedNails models.Product
+		testDB.First(&storedNails, nails.ID)
+		assert.Equal(t, 10, storedNails.Stock)
+
+		var ordersAfter int64
+		testDB.Model(&models.Order{}).Count(&ordersAfter)
+		assert.Equal(t, ordersBefore, ordersAfter)
+	})
+}
+
+func TestUpdateOrderStatusHandler(t *testing.T) {
+
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Furniture"}
+	testDB.Create(&category)
+
+	customer := models.Customer{Name: "Status Watcher", Email: "status@example.com", Phone: "0722222222"}
+	testDB.Create(&customer)
+
+	chair := models.Product{Name: "Chair", Price: models.NewMoney(450000, "KES"), Stock: 100, CategoryID: category.ID}
+	testDB.Create(&chair)
+
+	custID := customer.ID
+
+	placeOrder := func(t *testing.T) models.Order {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: chair.ID, Quantity: 4}},
+		}
+		recorder := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+
+		var response struct {
+			Order models.Order `json:"order"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return response.Order
+	}
+
+	setStatus := func(orderID uint, status string) *httptest.ResponseRecorder {
+		reqBody := handlers.UpdateOrderStatusRequest{Status: status, Note: "via test"}
+		return performOrderAuthenticatedRequest(router, http.MethodPatch, fmt.Sprintf("/api/orders/%d/status", orderID), reqBody, &custID)
+	}
+
+	t.Run("New orders start as pending with a history entry", func(t *testing.T) {
+		order := placeOrder(t)
+
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+
+		var history []models.OrderStatusHistory
+		testDB.Where("order_id = ?", order.ID).Find(&history)
+		assert.Len(t, history, 1)
+		assert.Equal(t, "", history[0].FromStatus)
+		assert.Equal(t, models.OrderStatusPending, history[0].ToStatus)
+		assert.Equal(t, fmt.Sprintf("customer:%d", customer.ID), history[0].Actor)
+	})
+
+	t.Run("Walks the happy path and records every transition", func(t *testing.T) {
+		order := placeOrder(t)
+
+		for _, status := range []string{models.OrderStatusPaid, models.OrderStatusProcessing, models.OrderStatusShipped, models.OrderStatusDelivered} {
+			recorder := setStatus(order.ID, status)
+			assert.Equal(t, http.StatusOK, recorder.Code, status)
+		}
+
+		var stored models.Order
+		testDB.First(&stored, order.ID)
+		assert.Equal(t, models.OrderStatusDelivered, stored.Status)
+
+		var history []models.OrderStatusHistory
+		testDB.Where("order_id = ?", order.ID).Order("id").Find(&history)
+		assert.Len(t, history, 5)
+		assert.Equal(t, models.OrderStatusShipped, history[4].FromStatus)
+		assert.Equal(t, models.OrderStatusDelivered, history[4].ToStatus)
+		assert.Equal(t, "via test", history[4].Note)
+	})
+
+	t.Run("Rejects a transition that skips states", func(t *testing.T) {
+		order := placeOrder(t)
+
+		recorder := setStatus(order.ID, models.OrderStatusShipped)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		var response map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, "cannot move order from pending to shipped", response["error"])
+		assert.ElementsMatch(t, []interface{}{"paid", "cancelled"}, response["allowed"])
+	})
+
+	t.Run("Cancelling returns the items to stock", func(t *testing.T) {
+		var before models.Product
+		testDB.First(&before, chair.ID)
+
+		order := placeOrder(t)
+		recorder := setStatus(order.ID, models.OrderStatusCancelled)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var after models.Product
+		testDB.First(&after, chair.ID)
+		assert.Equal(t, before.Stock, after.Stock)
+
+		// Terminal states cannot be left.
+		recorder = setStatus(order.ID, models.OrderStatusPaid)
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("Returns 400 for an unknown status", func(t *testing.T) {
+		recorder := setStatus(1, "lost")
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Returns 404 for an unknown order", func(t *testing.T) {
+		recorder := setStatus(99999, models.OrderStatusPaid)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestCustomerOrderHistoryHandlers(t *testing.T) {
+
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Kitchenware"}
+	testDB.Create(&category)
+
+	pan := models.Product{Name: "Frying Pan", Price: models.NewMoney(250000, "KES"), Stock: 100, CategoryID: category.ID}
+	testDB.Create(&pan)
+
+	alice := models.Customer{Name: "Alice", Email: "alice@example.com", Phone: "0733333333"}
+	bob := models.Customer{Name: "Bob", Email: "bob@example.com", Phone: "0744444444"}
+	testDB.Create(&alice)
+	testDB.Create(&bob)
+
+	// Three orders for Alice, oldest first, and one for Bob.
+	var aliceOrders []models.Order
+	for _, status := range []string{models.OrderStatusDelivered, models.OrderStatusPending, models.OrderStatusPaid} {
+		order := models.Order{
+			CustomerID: alice.ID,
+			Status:     status,
+			Items:      []models.OrderItem{{ProductID: pan.ID, Quantity: 1, Price: pan.Price}},
+		}
+		testDB.Create(&order)
+		aliceOrders = append(aliceOrders, order)
+	}
+	bobOrder := models.Order{CustomerID: bob.ID, Status: models.OrderStatusPending}
+	testDB.Create(&bobOrder)
+
+	aliceID := alice.ID
+
+	type listResponse struct {
+		Orders     []models.Order `json:"orders"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+
+	list := func(t *testing.T, query string) listResponse {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, "/api/orders"+query, nil, &aliceID)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		var response listResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("Lists only the customer's orders, newest first, with products", func(t *testing.T) {
+		response := list(t, "")
+
+		assert.Len(t, response.Orders, 3)
+		assert.Equal(t, aliceOrders[2].ID, response.Orders[0].ID)
+		assert.Equal(t, aliceOrders[0].ID, response.Orders[2].ID)
+		assert.Equal(t, "Frying Pan", response.Orders[0].Items[0].Product.Name)
+		assert.Nil(t, response.NextCursor)
+	})
+
+	t.Run("Pages through orders with a cursor", func(t *testing.T) {
+		first := list(t, "?limit=2")
+		assert.Len(t, first.Orders, 2)
+		assert.NotNil(t, first.NextCursor)
+
+		second := list(t, "?limit=2&cursor="+*first.NextCursor)
+		assert.Len(t, second.Orders, 1)
+		assert.Equal(t, aliceOrders[0].ID, second.Orders[0].ID)
+		assert.Nil(t, second.NextCursor)
+	})
+
+	t.Run("Filters by status", func(t *testing.T) {
+		response := list(t, "?status=pending")
+
+		assert.Len(t, response.Orders, 1)
+		assert.Equal(t, aliceOrders[1].ID, response.Orders[0].ID)
+	})
+
+	t.Run("Filters by date range", func(t *testing.T) {
+		assert.Len(t, list(t, "?to=2000-01-01").Orders, 0)
+		assert.Len(t, list(t, "?from=2000-01-01").Orders, 3)
+	})
+
+	t.Run("Returns 400 for an unknown status filter", func(t *testing.T) {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, "/api/orders?status=lost", nil, &aliceID)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Returns an order's details", func(t *testing.T) {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/orders/%d", aliceOrders[1].ID), nil, &aliceID)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var order models.Order
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &order))
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		assert.Len(t, order.Items, 1)
+		assert.Equal(t, "Frying Pan", order.Items[0].Product.Name)
+	})
+
+	t.Run("Hides other customers' orders", func(t *testing.T) {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/orders/%d", bobOrder.ID), nil, &aliceID)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Returns any customer's order to the back office", func(t *testing.T) {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, fmt.Sprintf("/api/admin/orders/%d", bobOrder.ID), nil, &aliceID)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var order models.Order
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &order))
+		assert.Equal(t, bob.ID, order.CustomerID)
+
+		recorder = performOrderAuthenticatedRequest(router, http.MethodGet, "/api/admin/orders/999999", nil, &aliceID)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Returns 401 without a session", func(t *testing.T) {
+		recorder := performOrderAuthenticatedRequest(router, http.MethodGet, "/api/orders", nil, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
+
+func TestCreateOrderNotifications(t *testing.T) {
+
+	router, testDB := setupOrderTestRouter(t)
+
+	category := models.Category{Name: "Garden"}
+	testDB.Create(&category)
+
+	customer := models.Customer{Name: "Notified Customer", Email: "notified@example.com", Phone: "+254700000001"}
+	testDB.Create(&customer)
+
+	hose := models.Product{Name: "Hose", Price: models.NewMoney(150000, "KES"), Stock: 10, CategoryID: category.ID}
+	testDB.Create(&hose)
+
+	custID := customer.ID
+
+	t.Run("Queues an SMS and an email in the outbox with the order", func(t *testing.T) {
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hose.ID, Quantity: 2}},
+		}
+		response := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var created struct {
+			Order struct {
+				ID uint `json:"ID"`
+			} `json:"order"`
+		}
+		json.Unmarshal(response.Body.Bytes(), &created)
+
+		var messages []models.OutboxMessage
+		testDB.Where("order_id = ?", created.Order.ID).Order("id").Find(&messages)
+		assert.Len(t, messages, 2)
+		if len(messages) != 2 {
+			return
+		}
+
+		sms := messages[0]
+		assert.Equal(t, models.OutboxChannelSMS, sms.Channel)
+		assert.Equal(t, models.OutboxStatusPending, sms.Status)
+		assert.Equal(t, "+254700000001", sms.Recipient)
+		assert.Contains(t, sms.Body, "2 x Hose")
+		assert.Contains(t, sms.Body, "Total: KES 3000.00")
+
+		email := messages[1]
+		assert.Equal(t, models.OutboxChannelEmail, email.Channel)
+		assert.Equal(t, "notified@example.com", email.Recipient)
+		assert.Contains(t, email.Subject, "Confirmation")
+		assert.Contains(t, email.Body, "Dear Notified Customer")
+		assert.Contains(t, email.HTMLBody, "KES 3000.00")
+	})
+
+	t.Run("Uses the customer's language", func(t *testing.T) {
+		swahili := models.Customer{Name: "Amina", Email: "amina@example.com", Phone: "+254700000002", Locale: "sw-KE"}
+		testDB.Create(&swahili)
+		swahiliID := swahili.ID
+
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hose.ID, Quantity: 1}},
+		}
+		response := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &swahiliID)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var sms models.OutboxMessage
+		testDB.Where("recipient = ?", "+254700000002").First(&sms)
+		assert.Contains(t, sms.Body, "Oda yako")
+		assert.Contains(t, sms.Body, "1 x Hose")
+
+		var email models.OutboxMessage
+		testDB.Where("recipient = ?", "amina@example.com").First(&email)
+		assert.Contains(t, email.Body, "Mpendwa Amina")
+	})
+
+	t.Run("Adds an unsubscribe link to the email when configured", func(t *testing.T) {
+		handlers.SetUnsubscribeLinks("https://shop.example.com/", "unsubscribe-secret")
+		t.Cleanup(func() { handlers.SetUnsubscribeLinks("", "") })
+
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hose.ID, Quantity: 1}},
+		}
+		response := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var email models.OutboxMessage
+		testDB.Where("recipient = ?", "notified@example.com").Order("id DESC").First(&email)
+		assert.Contains(t, email.Body, "https://shop.example.com/unsubscribe?token=")
+		assert.Contains(t, email.HTMLBody, "Unsubscribe")
+		assert.Contains(t, email.UnsubscribeURL, "https://shop.example.com/unsubscribe?token=", "sent as List-Unsubscribe")
+	})
+
+	t.Run("Skips channels the customer opted out of", func(t *testing.T) {
+		quiet := models.Customer{Name: "Quiet", Email: "quiet@example.com", Phone: "+254700000003"}
+		testDB.Create(&quiet)
+		testDB.Create(&models.NotificationPreference{CustomerID: quiet.ID, Channel: models.OutboxChannelSMS, Event: models.NotificationEventOrderPlaced, Enabled: false})
+		quietID := quiet.ID
+
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hose.ID, Quantity: 1}},
+		}
+		response := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &quietID)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var smsCount, emailCount int64
+		testDB.Model(&models.OutboxMessage{}).Where("recipient = ?", "+254700000003").Count(&smsCount)
+		testDB.Model(&models.OutboxMessage{}).Where("recipient = ?", "quiet@example.com").Count(&emailCount)
+		assert.Equal(t, int64(0), smsCount)
+		assert.Equal(t, int64(1), emailCount)
+	})
+
+	t.Run("Queues nothing when the order is rejected", func(t *testing.T) {
+		var before int64
+		testDB.Model(&models.OutboxMessage{}).Count(&before)
+
+		reqBody := handlers.CreateOrderRequest{
+			Items: []handlers.OrderItemRequest{{ProductID: hose.ID, Quantity: 500}},
+		}
+		response := performOrderAuthenticatedRequest(router, http.MethodPost, "/api/orders", reqBody, &custID)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		var after int64
+		testDB.Model(&models.OutboxMessage{}).Count(&after)
+		assert.Equal(t, before, after)
+	})
+}
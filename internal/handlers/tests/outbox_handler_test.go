@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupOutboxTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := testDB.AutoMigrate(&models.OutboxMessage{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+	testDB.Exec("DELETE FROM outbox_messages;")
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	r := gin.New()
+	api := r.Group("/api")
+	{
+		api.GET("/admin/outbox", handlers.ListOutboxMessages)
+		api.POST("/admin/outbox/:id/redrive", handlers.RedriveOutboxMessage)
+	}
+
+	return r, testDB
+}
+
+func TestOutboxAdminHandlers(t *testing.T) {
+	router, testDB := setupOutboxTestRouter(t)
+
+	orderID := uint(42)
+	dead := models.OutboxMessage{Channel: models.OutboxChannelSMS, Recipient: "+254700000000", Body: "placed", OrderID: &orderID, Status: models.OutboxStatusDead, Attempts: 8, LastError: "provider down", NextAttemptAt: time.Now()}
+	sent := models.OutboxMessage{Channel: models.OutboxChannelEmail, Recipient: "jane@example.com", Body: "placed", OrderID: &orderID, Status: models.OutboxStatusSent, Attempts: 1, NextAttemptAt: time.Now()}
+	testDB.Create(&dead)
+	testDB.Create(&sent)
+
+	t.Run("Lists dead messages by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/outbox", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			Messages []models.OutboxMessage `json:"messages"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Messages, 1)
+		assert.Equal(t, dead.ID, body.Messages[0].ID)
+		assert.Equal(t, "provider down", body.Messages[0].LastError)
+	})
+
+	t.Run("Filters by status and order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/outbox?status=sent&order_id=42", nil)
+		router.ServeHTTP(w, req)
+
+		var body struct {
+			Messages []models.OutboxMessage `json:"messages"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Messages, 1)
+		assert.Equal(t, sent.ID, body.Messages[0].ID)
+	})
+
+	t.Run("Rejects an unknown status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/outbox?status=lost", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Re-drives a dead message", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/admin/outbox/%d/redrive", dead.ID), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.OutboxMessage
+		testDB.First(&reloaded, dead.ID)
+		assert.Equal(t, models.OutboxStatusPending, reloaded.Status)
+		assert.Equal(t, 0, reloaded.Attempts)
+		assert.False(t, reloaded.NextAttemptAt.After(time.Now()))
+	})
+
+	t.Run("Refuses to re-drive a message that is not dead", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/admin/outbox/%d/redrive", sent.ID), nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Returns 404 for an unknown message", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/admin/outbox/99999/redrive", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,160 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/sessionstore"
+)
+
+// setupSessionTestServer serves the session endpoints behind a real
+// session store. /login?customer_id= stands in for the OIDC callback.
+func setupSessionTestServer(t *testing.T) (*httptest.Server, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.Customer{}, &models.Session{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	r := gin.New()
+	r.Use(sessions.Sessions("gosess", sessionstore.New(testDB, time.Hour, []byte("test-session-secret"))))
+	r.POST("/login", func(c *gin.Context) {
+		var custID uint
+		fmt.Sscan(c.Query("customer_id"), &custID)
+		sess := sessions.Default(c)
+		sess.Set(sessionstore.CustomerIDKey, custID)
+		sess.Save()
+	})
+	r.GET("/api/me/sessions", handlers.ListSessions)
+	r.DELETE("/api/me/sessions", handlers.RevokeOtherSessions)
+	r.DELETE("/api/me/sessions/:id", handlers.RevokeSession)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, testDB
+}
+
+// newBrowser returns a client with its own cookies, signed in as custID.
+func newBrowser(t *testing.T, server *httptest.Server, custID uint, userAgent string) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	sessionRequest(t, client, server, http.MethodPost, fmt.Sprintf("/login?customer_id=%d", custID), userAgent)
+	return client
+}
+
+func sessionRequest(t *testing.T, client *http.Client, server *httptest.Server, method, path, userAgent string) *http.Response {
+	req, _ := http.NewRequest(method, server.URL+path, nil)
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func listSessions(t *testing.T, client *http.Client, server *httptest.Server) []handlers.SessionResponse {
+	resp := sessionRequest(t, client, server, http.MethodGet, "/api/me/sessions", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Sessions []handlers.SessionResponse `json:"sessions"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body.Sessions
+}
+
+func TestSessions(t *testing.T) {
+	server, testDB := setupSessionTestServer(t)
+
+	jane := models.Customer{Name: "Jane", Email: "jane@example.com"}
+	testDB.Create(&jane)
+	other := models.Customer{Name: "Other", Email: "other@example.com"}
+	testDB.Create(&other)
+
+	laptop := newBrowser(t, server, jane.ID, "Laptop")
+	phone := newBrowser(t, server, jane.ID, "Phone")
+	tablet := newBrowser(t, server, jane.ID, "Tablet")
+	stranger := newBrowser(t, server, other.ID, "Stranger")
+
+	t.Run("Lists only the customer's own sessions", func(t *testing.T) {
+		list := listSessions(t, laptop, server)
+		assert.Len(t, list, 3)
+
+		current := 0
+		for _, s := range list {
+			assert.Contains(t, []string{"Laptop", "Phone", "Tablet"}, s.UserAgent)
+			if s.Current {
+				current++
+				assert.Equal(t, "Laptop", s.UserAgent)
+			}
+		}
+		assert.Equal(t, 1, current)
+	})
+
+	t.Run("Rejects requests without a signed-in customer", func(t *testing.T) {
+		resp := sessionRequest(t, &http.Client{}, server, http.MethodGet, "/api/me/sessions", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Revokes one session", func(t *testing.T) {
+		var phoneID uint
+		for _, s := range listSessions(t, laptop, server) {
+			if s.UserAgent == "Phone" {
+				phoneID = s.ID
+			}
+		}
+
+		resp := sessionRequest(t, laptop, server, http.MethodDelete, fmt.Sprintf("/api/me/sessions/%d", phoneID), "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sessionRequest(t, phone, server, http.MethodGet, "/api/me/sessions", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Len(t, listSessions(t, laptop, server), 2)
+	})
+
+	t.Run("Cannot revoke another customer's session", func(t *testing.T) {
+		strangerSessions := listSessions(t, stranger, server)
+		assert.Len(t, strangerSessions, 1)
+
+		resp := sessionRequest(t, laptop, server, http.MethodDelete, fmt.Sprintf("/api/me/sessions/%d", strangerSessions[0].ID), "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Len(t, listSessions(t, stranger, server), 1)
+	})
+
+	t.Run("Revokes every other session", func(t *testing.T) {
+		resp := sessionRequest(t, laptop, server, http.MethodDelete, "/api/me/sessions", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		list := listSessions(t, laptop, server)
+		assert.Len(t, list, 1)
+		assert.True(t, list[0].Current)
+
+		resp = sessionRequest(t, tablet, server, http.MethodGet, "/api/me/sessions", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Len(t, listSessions(t, stranger, server), 1)
+	})
+}
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+const testCallbackToken = "callback-secret"
+
+func setupSMSDeliveryTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := testDB.AutoMigrate(&models.SMSDelivery{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+	testDB.Exec("DELETE FROM sms_deliveries;")
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	r := gin.New()
+	r.POST("/callbacks/sms/delivery", handlers.SMSDeliveryCallback(testCallbackToken))
+	r.GET("/api/admin/sms-deliveries", handlers.ListSMSDeliveries)
+
+	return r, testDB
+}
+
+func postDeliveryReport(router *gin.Engine, token string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/callbacks/sms/delivery?token="+token, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSMSDeliveryCallback(t *testing.T) {
+	router, testDB := setupSMSDeliveryTestRouter(t)
+
+	orderID := uint(42)
+	delivery := models.SMSDelivery{OrderID: &orderID, PhoneNumber: "+254700000000", Provider: "africastalking", MessageID: "ATXid_1", Cost: "KES 0.8000", Status: "Sent", StatusCode: 101}
+	testDB.Create(&delivery)
+
+	t.Run("Rejects a request without the callback token", func(t *testing.T) {
+		w := postDeliveryReport(router, "wrong", url.Values{"id": {"ATXid_1"}, "status": {"Success"}})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Records an intermediate status", func(t *testing.T) {
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"id": {"ATXid_1"}, "status": {"Buffered"}, "phoneNumber": {"+254700000000"}, "networkCode": {"63902"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.SMSDelivery
+		testDB.First(&reloaded, delivery.ID)
+		assert.Equal(t, "Buffered", reloaded.Status)
+		assert.Equal(t, "63902", reloaded.NetworkCode)
+		assert.Nil(t, reloaded.DeliveredAt)
+	})
+
+	t.Run("Marks the message delivered on Success", func(t *testing.T) {
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"id": {"ATXid_1"}, "status": {"Success"}, "networkCode": {"63902"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.SMSDelivery
+		testDB.First(&reloaded, delivery.ID)
+		assert.Equal(t, "Success", reloaded.Status)
+		assert.NotNil(t, reloaded.DeliveredAt)
+		assert.Equal(t, "KES 0.8000", reloaded.Cost)
+	})
+
+	t.Run("Ignores a late intermediate report after a final one", func(t *testing.T) {
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"id": {"ATXid_1"}, "status": {"Sent"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.SMSDelivery
+		testDB.First(&reloaded, delivery.ID)
+		assert.Equal(t, "Success", reloaded.Status)
+	})
+
+	t.Run("Records the failure reason", func(t *testing.T) {
+		failed := models.SMSDelivery{PhoneNumber: "+254700000009", Provider: "africastalking", MessageID: "ATXid_2", Status: "Sent", StatusCode: 101}
+		testDB.Create(&failed)
+
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"id": {"ATXid_2"}, "status": {"Failed"}, "failureReason": {"AbsentSubscriber"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.SMSDelivery
+		testDB.First(&reloaded, failed.ID)
+		assert.Equal(t, "Failed", reloaded.Status)
+		assert.Equal(t, "AbsentSubscriber", reloaded.FailureReason)
+		assert.Nil(t, reloaded.DeliveredAt)
+	})
+
+	t.Run("Acknowledges an unknown message ID", func(t *testing.T) {
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"id": {"ATXid_unknown"}, "status": {"Success"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Returns 400 when required fields are missing", func(t *testing.T) {
+		w := postDeliveryReport(router, testCallbackToken, url.Values{"status": {"Success"}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Lists deliveries for an order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/sms-deliveries?order_id=42", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			Deliveries []models.SMSDelivery `json:"deliveries"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Deliveries, 1)
+		assert.Equal(t, "ATXid_1", body.Deliveries[0].MessageID)
+		assert.Equal(t, "Success", body.Deliveries[0].Status)
+	})
+}
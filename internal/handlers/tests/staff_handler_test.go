@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func setupStaffTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, *models.User) {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.User{}, &models.Session{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	t.Cleanup(func() { db.SetTestDB(originalDB) })
+
+	admin := &models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin}
+	testDB.Create(admin)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", admin)
+		c.Next()
+	})
+	r.GET("/api/admin/users", handlers.ListStaffUsers)
+	r.POST("/api/admin/users", handlers.CreateStaffUser)
+	r.PUT("/api/admin/users/:id/role", handlers.UpdateStaffRole)
+	r.DELETE("/api/admin/users/:id", handlers.DeleteStaffUser)
+
+	return r, testDB, admin
+}
+
+func performStaffRequest(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaffUsers(t *testing.T) {
+	router, testDB, admin := setupStaffTestRouter(t)
+
+	var maya models.User
+
+	t.Run("Grants a role to a new staff member", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPost, "/api/admin/users", gin.H{"name": "Maya", "email": "Maya@Example.com", "role": models.RoleCatalogManager})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		json.Unmarshal(w.Body.Bytes(), &maya)
+		assert.Equal(t, "maya@example.com", maya.Email)
+		assert.Equal(t, models.RoleCatalogManager, maya.Role)
+	})
+
+	t.Run("Rejects duplicates and unknown roles", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPost, "/api/admin/users", gin.H{"email": "maya@example.com", "role": models.RoleSupport})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performStaffRequest(router, http.MethodPost, "/api/admin/users", gin.H{"email": "bob@example.com", "role": "superuser"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = performStaffRequest(router, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", maya.ID), gin.H{"role": "superuser"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Lists staff, optionally by role", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodGet, "/api/admin/users", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			Users []models.User `json:"users"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Users, 2)
+
+		w = performStaffRequest(router, http.MethodGet, "/api/admin/users?role="+models.RoleCatalogManager, nil)
+		json.Unmarshal(w.Body.Bytes(), &body)
+		assert.Len(t, body.Users, 1)
+		assert.Equal(t, "maya@example.com", body.Users[0].Email)
+	})
+
+	t.Run("Changes a role", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", maya.ID), gin.H{"role": models.RoleFulfilment})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.User
+		testDB.First(&reloaded, maya.ID)
+		assert.Equal(t, models.RoleFulfilment, reloaded.Role)
+		assert.Equal(t, models.RoleSourceManual, reloaded.RoleSource)
+	})
+
+	t.Run("Keeps at least one admin", func(t *testing.T) {
+		w := performStaffRequest(router, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", admin.ID), gin.H{"role": models.RoleSupport})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performStaffRequest(router, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", admin.ID), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = performStaffRequest(router, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", maya.ID), gin.H{"role": models.RoleAdmin})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = performStaffRequest(router, http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", admin.ID), gin.H{"role": models.RoleSupport})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Removes a staff user and ends their sessions", func(t *testing.T) {
+		userID := admin.ID
+		testDB.Create(&models.Session{TokenHash: "staff-session", UserID: &userID, ExpiresAt: time.Now().Add(time.Hour)})
+
+		w := performStaffRequest(router, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", admin.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var sessions int64
+		testDB.Model(&models.Session{}).Where("user_id = ?", admin.ID).Count(&sessions)
+		assert.Equal(t, int64(0), sessions)
+
+		w = performStaffRequest(router, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", admin.ID), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
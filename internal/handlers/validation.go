@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func init() {
+	// Let binding tags such as gt=0 on a models.Money field compare its
+	// amount in minor units.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			if money, ok := field.Interface().(models.Money); ok {
+				return money.Amount
+			}
+			return nil
+		}, models.Money{})
+	}
+}
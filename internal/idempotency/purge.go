@@ -0,0 +1,36 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// PurgeExpired deletes idempotency keys that expired by now and returns
+// how many. Expired keys are otherwise only dropped when reused.
+func PurgeExpired(database *gorm.DB, now time.Time) (int64, error) {
+	result := database.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// RunPurge calls PurgeExpired every interval until ctx is cancelled.
+func RunPurge(ctx context.Context, database *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := PurgeExpired(database, time.Now()); err != nil {
+			log.Printf("idempotency: purging expired keys: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
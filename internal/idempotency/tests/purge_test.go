@@ -0,0 +1,35 @@
+package idempotency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/idempotency"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func TestPurgeExpired(t *testing.T) {
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+	if err := testDB.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("failed to auto-migrate models: %v", err)
+	}
+
+	now := time.Now()
+	testDB.Create(&models.IdempotencyKey{CustomerID: 1, Key: "old", RequestHash: "h", ExpiresAt: now.Add(-time.Minute)})
+	testDB.Create(&models.IdempotencyKey{CustomerID: 1, Key: "live", RequestHash: "h", ExpiresAt: now.Add(time.Hour)})
+
+	purged, err := idempotency.PurgeExpired(testDB, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	var remaining []string
+	testDB.Model(&models.IdempotencyKey{}).Pluck("key", &remaining)
+	assert.Equal(t, []string{"live"}, remaining)
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Scopes an API key can be granted.
+const (
+	ScopeCatalogRead    = "catalog:read"
+	ScopeCatalogWrite   = "catalog:write"
+	ScopeInventoryRead  = "inventory:read"
+	ScopeInventoryWrite = "inventory:write"
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersWrite    = "orders:write"
+)
+
+// APIKeyScopes lists every valid scope.
+var APIKeyScopes = []string{ScopeCatalogRead, ScopeCatalogWrite, ScopeInventoryRead, ScopeInventoryWrite, ScopeOrdersRead, ScopeOrdersWrite}
+
+// IsValidAPIKeyScope reports whether scope is a known scope.
+func IsValidAPIKeyScope(scope string) bool {
+	for _, known := range APIKeyScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyPrefix starts every key, so leaked keys are easy to recognise and
+// scan for.
+const apiKeyPrefix = "ek_"
+
+// APIKey lets another system call the API without a person signing in.
+// Keys look like "ek_<prefix>_<secret>". Only the prefix, which identifies
+// the key, and a SHA-256 hash of the whole key are stored; the key itself
+// is shown once, when it is created.
+type APIKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	Prefix      string `gorm:"size:16;uniqueIndex;not null"`
+	KeyHash     string `gorm:"size:64;not null" json:"-"`
+	Scopes      string `gorm:"not null"` // space-separated
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedByID *uint
+	RotatedToID *uint // the key that replaced this one
+	CreatedAt   time.Time
+}
+
+// ScopeList returns the key's scopes.
+func (k APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key was granted scope.
+func (k APIKey) HasScope(scope string) bool {
+	for _, granted := range k.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can be used at now.
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// NewAPIKeySecret generates a key, returning it along with the prefix and
+// hash to store.
+func NewAPIKeySecret() (key, prefix, hash string, err error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(id)
+	key = apiKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// ParseAPIKeyPrefix returns the prefix of key, or false if key is not
+// shaped like an API key.
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// HashAPIKey returns the value stored in APIKey.KeyHash for key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,15 @@
 package models
 
+// Customer is a shopper. Email is empty for logins whose provider sent
+// none, so it is only unique among customers that have one.
 type Customer struct {
 
 	ID       uint   `gorm:"primaryKey"`
     Name     string `gorm:"not null"`
-    Email    string `gorm:"uniqueIndex;not null"`
+    Email    string `gorm:"uniqueIndex:idx_customers_email_set,where:email <> '';not null"`
     Phone    string `gorm:"not null"`
-    OIDCID   string `gorm:"uniqueIndex"` // OpenID Connect identifier
+    Locale   string `gorm:"size:16"`     // BCP 47 tag, e.g. "sw-KE"; picks the notification language
+
+    Identities              []CustomerIdentity       `json:"-"`
+    NotificationPreferences []NotificationPreference `json:"-"`
 }
\ No newline at end of file
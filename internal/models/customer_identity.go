@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CustomerIdentity links a customer to an account at an OpenID Connect
+// provider, so one customer can sign in with several. An account is
+// identified by its issuer and subject together: a subject is only unique
+// within the issuer that assigned it.
+type CustomerIdentity struct {
+	ID          uint   `gorm:"primaryKey"`
+	CustomerID  uint   `gorm:"index;not null"`
+	Provider    string `gorm:"size:64;not null"` // configured provider name, e.g. "google"
+	Issuer      string `gorm:"size:255;not null;uniqueIndex:idx_customer_identities_issuer_subject"`
+	Subject     string `gorm:"size:255;not null;uniqueIndex:idx_customer_identities_issuer_subject"`
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the outcome of a request sent with an
+// Idempotency-Key header so a retry can be answered with the original
+// response. ResponseStatus is zero while the first request is in flight;
+// LockedUntil then bounds how long that request holds the key, so a claim
+// left behind by a crashed process can be taken over by a retry.
+type IdempotencyKey struct {
+    ID             uint      `gorm:"primaryKey"`
+    CustomerID     uint      `gorm:"uniqueIndex:idx_idempotency_customer_key;not null"`
+    Key            string    `gorm:"uniqueIndex:idx_idempotency_customer_key;size:255;not null"`
+    RequestHash    string    `gorm:"size:64;not null"`
+    ResponseStatus int
+    ResponseBody   []byte
+    LockedUntil    *time.Time
+    CreatedAt      time.Time
+    ExpiresAt      time.Time `gorm:"index;not null"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Reasons recorded on InventoryMovement rows.
+const (
+    InventoryReasonInitial    = "initial"
+    InventoryReasonRestock    = "restock"
+    InventoryReasonAdjustment = "adjustment"
+    InventoryReasonOrder      = "order"
+    InventoryReasonCancelled  = "order_cancelled"
+)
+
+// InventoryMovement is one entry in the stock ledger. Change is signed, and
+// BalanceAfter is the product's stock once the change was applied.
+type InventoryMovement struct {
+    ID           uint   `gorm:"primaryKey"`
+    ProductID    uint   `gorm:"index;not null"`
+    Change       int    `gorm:"not null"`
+    BalanceAfter int    `gorm:"not null"`
+    Reason       string `gorm:"size:32;not null"`
+    OrderID      *uint  `gorm:"index"`
+    Note         string
+    CreatedAt    time.Time
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is assumed whenever a price arrives without a currency
+// code, and is the currency legacy float prices are migrated into.
+const DefaultCurrency = "KES"
+
+// zeroDecimalCurrencies lists ISO 4217 currencies without minor units. Every
+// other currency is treated as having two decimal places.
+var zeroDecimalCurrencies = map[string]bool{
+	"JPY": true,
+	"KRW": true,
+	"RWF": true,
+	"UGX": true,
+}
+
+// Money is an exact monetary amount held as an integer count of the
+// currency's minor unit (cents for KES). It is embedded in models with an
+// embeddedPrefix, e.g. price_amount and price_currency, and travels as
+// {"amount": "1200.00", "currency": "KES"} in JSON.
+type Money struct {
+	Amount   int64  `gorm:"not null"`
+	Currency string `gorm:"size:3;not null"`
+}
+
+// NewMoney builds a Money from an amount already in minor units.
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{Amount: minorUnits, Currency: currency}
+}
+
+// ParseMoney reads a decimal string such as "1200.5" into minor units of
+// currency without going through float64. More decimal places than the
+// currency supports is an error rather than a silent rounding.
+func ParseMoney(value string, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	currency = strings.ToUpper(currency)
+	if len(currency) != 3 {
+		return Money{}, fmt.Errorf("invalid currency code %q", currency)
+	}
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if !isDigits(whole) || (hasFraction && !isDigits(fraction)) {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+
+	exponent := minorUnitExponent(currency)
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", value, exponent, currency)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// isDigits reports whether s is one or more ASCII digits, with no sign.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal renders the amount in major units, e.g. "1200.00".
+func (m Money) Decimal() string {
+	exponent := minorUnitExponent(m.Currency)
+	if exponent == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, amount)
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// String renders the amount with its currency code, e.g. "KES 1200.00".
+func (m Money) String() string {
+	return m.Currency + " " + m.Decimal()
+}
+
+// Add returns the sum of two amounts in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("cannot add %s to %s", other.Currency, m.Currency)
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns the amount multiplied by a whole quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON accepts the amount either as a decimal string or as a bare
+// JSON number; both are parsed from their literal text so no float rounding
+// is involved. A missing currency falls back to DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.New("money must be an object with amount and currency")
+	}
+
+	if len(raw.Amount) == 0 || string(raw.Amount) == "null" {
+		return errors.New("money amount is required")
+	}
+
+	amount := string(raw.Amount)
+	if strings.HasPrefix(amount, `"`) {
+		if err := json.Unmarshal(raw.Amount, &amount); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseMoney(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func minorUnitExponent(currency string) int {
+	if zeroDecimalCurrencies[currency] {
+		return 0
+	}
+	return 2
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Notification events a customer can opt in to or out of.
+const (
+	NotificationEventOrderPlaced  = "order_placed"
+	NotificationEventOrderShipped = "order_shipped"
+)
+
+// NotificationEvents and NotificationChannels list every valid preference
+// key, in the order preferences are presented.
+var (
+	NotificationEvents   = []string{NotificationEventOrderPlaced, NotificationEventOrderShipped}
+	NotificationChannels = []string{OutboxChannelSMS, OutboxChannelEmail}
+)
+
+// IsValidNotificationEvent reports whether event is a known event.
+func IsValidNotificationEvent(event string) bool {
+	for _, known := range NotificationEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidNotificationChannel reports whether channel is a known channel.
+func IsValidNotificationChannel(channel string) bool {
+	for _, known := range NotificationChannels {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationPreference records whether a customer wants a given event
+// over a given channel. Customers are opted in by default, so a missing row
+// means enabled.
+type NotificationPreference struct {
+	ID         uint   `gorm:"primaryKey"`
+	CustomerID uint   `gorm:"uniqueIndex:idx_notification_preference;not null"`
+	Channel    string `gorm:"uniqueIndex:idx_notification_preference;size:16;not null"`
+	Event      string `gorm:"uniqueIndex:idx_notification_preference;size:32;not null"`
+	Enabled    bool   `gorm:"not null"`
+	UpdatedAt  time.Time
+}
@@ -2,12 +2,58 @@ package models
 
 import "time"
 
+// Order statuses. New orders start as OrderStatusPending and may only move
+// along the edges in orderStatusTransitions.
+const (
+    OrderStatusPending    = "pending"
+    OrderStatusPaid       = "paid"
+    OrderStatusProcessing = "processing"
+    OrderStatusShipped    = "shipped"
+    OrderStatusDelivered  = "delivered"
+    OrderStatusCancelled  = "cancelled"
+    OrderStatusRefunded   = "refunded"
+)
+
+var orderStatusTransitions = map[string][]string{
+    OrderStatusPending:    {OrderStatusPaid, OrderStatusCancelled},
+    OrderStatusPaid:       {OrderStatusProcessing, OrderStatusCancelled, OrderStatusRefunded},
+    OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+    OrderStatusShipped:    {OrderStatusDelivered, OrderStatusRefunded},
+    OrderStatusDelivered:  {OrderStatusRefunded},
+    OrderStatusCancelled:  {},
+    OrderStatusRefunded:   {},
+}
+
+// IsValidOrderStatus reports whether status is one of the known statuses.
+func IsValidOrderStatus(status string) bool {
+    _, ok := orderStatusTransitions[status]
+    return ok
+}
+
+// AllowedOrderTransitions lists the statuses an order may move to next.
+func AllowedOrderTransitions(from string) []string {
+    return append([]string{}, orderStatusTransitions[from]...)
+}
+
+// CanTransitionOrder reports whether an order may move from one status to
+// another.
+func CanTransitionOrder(from, to string) bool {
+    for _, allowed := range orderStatusTransitions[from] {
+        if allowed == to {
+            return true
+        }
+    }
+    return false
+}
+
 type Order struct {
     ID         uint        `gorm:"primaryKey"`
     CustomerID uint        `gorm:"index;not null"`
     Customer   Customer
+    Status     string      `gorm:"size:20;not null;default:pending;index"`
     CreatedAt  time.Time
-    Items      []OrderItem `gorm:"foreignKey:OrderID"`
+    Items      []OrderItem          `gorm:"foreignKey:OrderID"`
+    History    []OrderStatusHistory `gorm:"foreignKey:OrderID"`
 }
 
 type OrderItem struct {
@@ -15,7 +61,20 @@ type OrderItem struct {
     OrderID   uint    `gorm:"index;not null"`
     ProductID uint    `gorm:"index;not null"`
     Quantity  uint    `gorm:"not null"`
-    Price     float64 `gorm:"not null"`
+    Price     Money   `gorm:"embedded;embeddedPrefix:price_"`
     Product   Product
     CreatedAt time.Time
-}
\ No newline at end of file
+}
+
+// OrderStatusHistory records each status change. FromStatus is empty for
+// the entry written when the order is placed. Actor identifies who made the
+// change, e.g. "customer:12".
+type OrderStatusHistory struct {
+    ID         uint   `gorm:"primaryKey"`
+    OrderID    uint   `gorm:"index;not null"`
+    FromStatus string `gorm:"size:20"`
+    ToStatus   string `gorm:"size:20;not null"`
+    Actor      string `gorm:"not null"`
+    Note       string
+    CreatedAt  time.Time
+}
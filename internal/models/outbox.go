@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Channels an OutboxMessage can be delivered over.
+const (
+    OutboxChannelSMS   = "sms"
+    OutboxChannelEmail = "email"
+)
+
+// Delivery states of an OutboxMessage. A message stays pending between
+// failed attempts and becomes dead once it runs out of attempts.
+const (
+    OutboxStatusPending = "pending"
+    OutboxStatusSent    = "sent"
+    OutboxStatusDead    = "dead"
+)
+
+// OutboxMessage is a notification written in the same transaction as the
+// change that triggered it and delivered later by the outbox worker.
+// NextAttemptAt is when the worker may next pick the message up; it is also
+// pushed forward while a delivery is in progress so no other worker claims
+// the same message. UnsubscribeURL is sent as an email's List-Unsubscribe
+// header.
+type OutboxMessage struct {
+    ID             uint      `gorm:"primaryKey"`
+    Channel        string    `gorm:"size:16;not null"`
+    Recipient      string    `gorm:"not null"`
+    Subject        string
+    Body           string    `gorm:"type:text;not null"`
+    HTMLBody       string    `gorm:"type:text"`
+    UnsubscribeURL string    `gorm:"type:text"`
+    OrderID        *uint     `gorm:"index"`
+    Status         string    `gorm:"size:16;not null;default:pending;index:idx_outbox_due,priority:1"`
+    Attempts       int       `gorm:"not null;default:0"`
+    NextAttemptAt  time.Time `gorm:"not null;index:idx_outbox_due,priority:2"`
+    LastError      string    `gorm:"type:text"`
+    SentAt         *time.Time
+    CreatedAt      time.Time
+    UpdatedAt      time.Time
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultPhoneCountry is assumed for numbers written in national format,
+// e.g. 0712 345 678, when no other default is configured.
+const DefaultPhoneCountry = "KE"
+
+// ErrInvalidPhoneNumber is returned for numbers that cannot be turned into
+// a plausible E.164 number.
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// phoneCountry holds what is needed to read a national number: the
+// country calling code, the trunk prefix dialled before national numbers
+// (if any) and the allowed lengths of the national significant number.
+type phoneCountry struct {
+	callingCode string
+	trunkPrefix string
+	nsnLengths  []int
+}
+
+var phoneCountries = map[string]phoneCountry{
+	"KE": {"254", "0", []int{9}},
+	"UG": {"256", "0", []int{9}},
+	"TZ": {"255", "0", []int{9}},
+	"RW": {"250", "0", []int{9}},
+	"ET": {"251", "0", []int{9}},
+	"NG": {"234", "0", []int{8, 10}},
+	"ZA": {"27", "0", []int{9}},
+	"GB": {"44", "0", []int{9, 10}},
+	"US": {"1", "", []int{10}},
+}
+
+// countriesByCallingCode lets a number in international format be checked
+// against its country's length rules.
+var countriesByCallingCode = func() map[string]phoneCountry {
+	byCode := make(map[string]phoneCountry, len(phoneCountries))
+	for _, country := range phoneCountries {
+		byCode[country.callingCode] = country
+	}
+	return byCode
+}()
+
+// NormalizePhone rewrites a phone number into E.164, e.g. "+254712345678".
+// Spaces, dashes, dots and brackets are ignored. Numbers starting with + or
+// 00 are read as international; anything else is read as a national number
+// of defaultCountry (an ISO 3166 alpha-2 code), with or without its trunk
+// prefix or calling code. Numbers for countries in phoneCountries must have
+// a valid length for that country; others only need 8 to 15 digits.
+func NormalizePhone(raw string, defaultCountry string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	if cleaned == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidPhoneNumber)
+	}
+
+	international := false
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+		cleaned, international = cleaned[1:], true
+	case strings.HasPrefix(cleaned, "00"):
+		cleaned, international = cleaned[2:], true
+	}
+
+	for _, r := range cleaned {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %q contains %q", ErrInvalidPhoneNumber, raw, r)
+		}
+	}
+
+	if !international {
+		country, ok := phoneCountries[strings.ToUpper(defaultCountry)]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported default country %q", ErrInvalidPhoneNumber, defaultCountry)
+		}
+
+		switch {
+		case country.trunkPrefix != "" && strings.HasPrefix(cleaned, country.trunkPrefix):
+			cleaned = country.callingCode + strings.TrimPrefix(cleaned, country.trunkPrefix)
+		case strings.HasPrefix(cleaned, country.callingCode) && validNSNLength(country, len(cleaned)-len(country.callingCode)):
+			// Already carries the calling code, just without the +.
+		default:
+			cleaned = country.callingCode + cleaned
+		}
+	}
+
+	if err := validateE164Digits(cleaned); err != nil {
+		return "", fmt.Errorf("%w: %q %v", ErrInvalidPhoneNumber, raw, err)
+	}
+
+	return "+" + cleaned, nil
+}
+
+func validateE164Digits(digits string) error {
+	if len(digits) < 8 || len(digits) > 15 {
+		return errors.New("must have between 8 and 15 digits")
+	}
+	if digits[0] == '0' {
+		return errors.New("country code cannot start with 0")
+	}
+
+	// Calling codes are prefix-free, so at most one of 1 to 3 leading
+	// digits can match.
+	for length := 1; length <= 3; length++ {
+		country, ok := countriesByCallingCode[digits[:length]]
+		if !ok {
+			continue
+		}
+
+		nsn := digits[length:]
+		if !validNSNLength(country, len(nsn)) {
+			return fmt.Errorf("has the wrong number of digits for +%s", country.callingCode)
+		}
+		if country.trunkPrefix != "" && strings.HasPrefix(nsn, country.trunkPrefix) {
+			return fmt.Errorf("should not include the trunk prefix %s after +%s", country.trunkPrefix, country.callingCode)
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func validNSNLength(country phoneCountry, length int) bool {
+	for _, allowed := range country.nsnLengths {
+		if length == allowed {
+			return true
+		}
+	}
+	return false
+}
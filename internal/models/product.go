@@ -4,7 +4,8 @@ type Product struct {
 	
     ID         uint     `gorm:"primaryKey"`
     Name       string   `gorm:"not null"`
-    Price      float64  `gorm:"not null"`
+    Price      Money    `gorm:"embedded;embeddedPrefix:price_"`
+    Stock      int      `gorm:"not null;default:0"`
     CategoryID uint     `gorm:"index;not null"`
     Category   Category
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Session is a browser session kept on the server. The cookie carries a
+// signed random token and only its SHA-256 hash is stored, so reading the
+// table does not hand out live sessions. Deleting the row ends the session.
+type Session struct {
+	ID         uint   `gorm:"primaryKey"`
+	TokenHash  string `gorm:"size:64;uniqueIndex;not null"`
+	CustomerID *uint  `gorm:"index"`
+	UserID     *uint  `gorm:"index"`
+	Data       []byte
+	UserAgent  string `gorm:"size:512"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index;not null"`
+}
+
+// HashSessionToken returns the value stored in Session.TokenHash for token.
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
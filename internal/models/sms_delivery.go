@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Final delivery report statuses from Africa's Talking. Earlier reports
+// (Sent, Submitted, Buffered) may still arrive after one of these and must
+// not overwrite it.
+var finalSMSStatuses = map[string]bool{
+    "Success":          true,
+    "Failed":           true,
+    "Rejected":         true,
+    "AbsentSubscriber": true,
+    "Expired":          true,
+}
+
+// IsFinalSMSStatus reports whether status is a terminal delivery status.
+func IsFinalSMSStatus(status string) bool {
+    return finalSMSStatuses[status]
+}
+
+// SMSDelivery records one text message handed to the SMS provider: the
+// provider's message ID and cost as returned when it was sent, and the
+// latest status from its delivery reports. DeliveredAt is set once the
+// handset confirms receipt.
+type SMSDelivery struct {
+    ID              uint   `gorm:"primaryKey"`
+    OutboxMessageID *uint  `gorm:"index"`
+    OrderID         *uint  `gorm:"index"`
+    PhoneNumber     string `gorm:"index;not null"`
+    Provider        string `gorm:"size:32;not null"`
+    MessageID       string `gorm:"index"`
+    Cost            string `gorm:"size:32"`
+    Status          string `gorm:"size:32"`
+    StatusCode      int
+    NetworkCode     string `gorm:"size:16"`
+    FailureReason   string
+    DeliveredAt     *time.Time
+    CreatedAt       time.Time
+    UpdatedAt       time.Time
+}
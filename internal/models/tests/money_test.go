@@ -0,0 +1,99 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Run("Parses decimal strings into minor units", func(t *testing.T) {
+		cases := map[string]int64{
+			"1200":    120000,
+			"1200.5":  120050,
+			"0.1":     10,
+			"0.07":    7,
+			"-3.25":   -325,
+			"1999.99": 199999,
+		}
+
+		for input, expected := range cases {
+			money, err := models.ParseMoney(input, "KES")
+			assert.NoError(t, err, input)
+			assert.Equal(t, models.NewMoney(expected, "KES"), money, input)
+		}
+	})
+
+	t.Run("Defaults the currency and upper-cases it", func(t *testing.T) {
+		money, err := models.ParseMoney("10", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "KES", money.Currency)
+
+		money, err = models.ParseMoney("10", "usd")
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", money.Currency)
+	})
+
+	t.Run("Rejects more decimal places than the currency allows", func(t *testing.T) {
+		_, err := models.ParseMoney("10.005", "KES")
+		assert.Error(t, err)
+
+		_, err = models.ParseMoney("10.5", "UGX")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects malformed amounts", func(t *testing.T) {
+		for _, input := range []string{"", "abc", "1.", ".5", "1e3", "1,000", "--5", "-+5", "+5", "1.-5", "1.+5", "- 5"} {
+			_, err := models.ParseMoney(input, "KES")
+			assert.Error(t, err, input)
+		}
+	})
+}
+
+func TestMoneyArithmeticAndFormatting(t *testing.T) {
+	price := models.NewMoney(199999, "KES")
+
+	assert.Equal(t, "1999.99", price.Decimal())
+	assert.Equal(t, "KES 1999.99", price.String())
+	assert.Equal(t, "0.05", models.NewMoney(5, "KES").Decimal())
+	assert.Equal(t, "-0.05", models.NewMoney(-5, "KES").Decimal())
+	assert.Equal(t, "5000", models.NewMoney(5000, "UGX").Decimal())
+
+	// 0.1 + 0.2 is the classic float drift case.
+	sum, err := models.NewMoney(10, "KES").Add(models.NewMoney(20, "KES"))
+	assert.NoError(t, err)
+	assert.Equal(t, "0.30", sum.Decimal())
+
+	assert.Equal(t, models.NewMoney(599997, "KES"), price.Mul(3))
+
+	_, err = price.Add(models.NewMoney(100, "USD"))
+	assert.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Run("Marshals the amount as a decimal string", func(t *testing.T) {
+		raw, err := json.Marshal(models.NewMoney(120050, "KES"))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"amount": "1200.50", "currency": "KES"}`, string(raw))
+	})
+
+	t.Run("Unmarshals string and number amounts exactly", func(t *testing.T) {
+		var fromString, fromNumber models.Money
+
+		assert.NoError(t, json.Unmarshal([]byte(`{"amount": "0.29", "currency": "KES"}`), &fromString))
+		assert.NoError(t, json.Unmarshal([]byte(`{"amount": 0.29}`), &fromNumber))
+		assert.Equal(t, models.NewMoney(29, "KES"), fromString)
+		assert.Equal(t, models.NewMoney(29, "KES"), fromNumber)
+	})
+
+	t.Run("Rejects a missing amount", func(t *testing.T) {
+		var money models.Money
+
+		assert.Error(t, json.Unmarshal([]byte(`{"currency": "KES"}`), &money))
+		assert.Error(t, json.Unmarshal([]byte(`12.5`), &money))
+	})
+}
@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	t.Run("Normalizes Kenyan numbers in common formats", func(t *testing.T) {
+		cases := []string{
+			"0712345678",
+			"0712 345 678",
+			"0712-345-678",
+			"712345678",
+			"254712345678",
+			"+254712345678",
+			"+254 712 345 678",
+			"00254712345678",
+			"(0712) 345.678",
+		}
+
+		for _, input := range cases {
+			phone, err := models.NormalizePhone(input, "KE")
+			assert.NoError(t, err, input)
+			assert.Equal(t, "+254712345678", phone, input)
+		}
+	})
+
+	t.Run("Uses the default country for national numbers", func(t *testing.T) {
+		phone, err := models.NormalizePhone("0772 123456", "ug")
+		assert.NoError(t, err)
+		assert.Equal(t, "+256772123456", phone)
+
+		phone, err = models.NormalizePhone("(415) 555-2671", "US")
+		assert.NoError(t, err)
+		assert.Equal(t, "+14155552671", phone)
+	})
+
+	t.Run("Keeps international numbers for other countries", func(t *testing.T) {
+		phone, err := models.NormalizePhone("+44 20 7946 0958", "KE")
+		assert.NoError(t, err)
+		assert.Equal(t, "+442079460958", phone)
+
+		// Not in the country table: only the overall length is checked.
+		phone, err = models.NormalizePhone("+33 6 12 34 56 78", "KE")
+		assert.NoError(t, err)
+		assert.Equal(t, "+33612345678", phone)
+	})
+
+	t.Run("Rejects invalid numbers", func(t *testing.T) {
+		cases := []string{
+			"",
+			"   ",
+			"not a number",
+			"0712 34567",
+			"07123456789",
+			"+254 0712 345 678",
+			"+2547123",
+			"+1234567890123456",
+			"0712345678x",
+		}
+
+		for _, input := range cases {
+			_, err := models.NormalizePhone(input, "KE")
+			assert.ErrorIs(t, err, models.ErrInvalidPhoneNumber, input)
+		}
+	})
+
+	t.Run("Rejects national numbers for an unsupported default country", func(t *testing.T) {
+		_, err := models.NormalizePhone("0612345678", "FR")
+		assert.ErrorIs(t, err, models.ErrInvalidPhoneNumber)
+
+		phone, err := models.NormalizePhone("+33612345678", "FR")
+		assert.NoError(t, err)
+		assert.Equal(t, "+33612345678", phone)
+	})
+}
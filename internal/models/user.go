@@ -2,11 +2,60 @@ package models
 
 import "time"
 
+// Staff roles. Admins may do everything; the other roles only reach the
+// routes for their area.
+const (
+	RoleAdmin          = "admin"
+	RoleCatalogManager = "catalog-manager"
+	RoleFulfilment     = "fulfilment"
+	RoleSupport        = "support"
+)
+
+// Where a staff user's role comes from. IdP roles are set from group
+// claims on every login; manual ones are granted through the admin API.
+const (
+	RoleSourceManual = "manual"
+	RoleSourceIdP    = "idp"
+)
+
+// Roles lists every role that can be granted.
+var Roles = []string{RoleAdmin, RoleCatalogManager, RoleFulfilment, RoleSupport}
+
+// IsValidRole reports whether role is a known staff role.
+func IsValidRole(role string) bool {
+	for _, known := range Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// User is a member of staff. Staff are kept apart from shoppers
+// (Customer): a person can be both, but a customer session never carries
+// staff rights.
 type User struct {
 
 	ID         uint       `gorm:"primaryKey"`
     Name       string     `gorm:"not null"`
     Email      string     `gorm:"uniqueIndex;not null"`
 	Role       string     `gorm:"not null"`
+	RoleSource string     `gorm:"size:16;not null;default:manual"`
+	Issuer     *string    `gorm:"uniqueIndex:idx_users_issuer_subject;size:255"` // OIDC issuer and subject, recorded at login;
+	Subject    *string    `gorm:"uniqueIndex:idx_users_issuer_subject;size:255"` // together they resolve bearer tokens
 	CreatedAt  time.Time
-}
\ No newline at end of file
+}
+
+// HasRole reports whether the user holds one of roles. Admins hold every
+// role.
+func (u User) HasRole(roles ...string) bool {
+	if u.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
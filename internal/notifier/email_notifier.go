@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
+	"net/mail"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -15,84 +15,96 @@ import (
 	"github.com/Keoroanthony/go-ecommerce/configs"
 )
 
-func SendEmail(recipientEmail string, customerName string, orderID uint, totalAmount float64) error {
-	cfg := config.LoadEmailConfig()
+// SESSender sends email through AWS SES.
+type SESSender struct {
+	client      *ses.Client
+	senderEmail string
+}
 
-	
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
+func NewSESSender(ctx context.Context, cfg config.EmailConfig) (*SESSender, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
 		awsconfig.WithRegion(cfg.AWSRegion),
 		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, "")),
 	)
 	if err != nil {
-
-		log.Printf("Failed to load AWS SDK config for email to %s (order %d): %v", recipientEmail, orderID, err)
-		return fmt.Errorf("failed to load AWS SDK config: %w", err)
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
 	}
 
-	client := ses.NewFromConfig(awsCfg)
+	return &SESSender{client: ses.NewFromConfig(awsCfg), senderEmail: cfg.SenderEmail}, nil
+}
 
-	if cfg.SenderEmail == "" {
+func (s *SESSender) SendEmail(ctx context.Context, msg EmailMessage) error {
+	if s.senderEmail == "" {
 		return fmt.Errorf("sender email address is not configured in environment variables")
 	}
-	if recipientEmail == "" {
+	if msg.To == "" {
 		return fmt.Errorf("recipient email address is empty")
 	}
 
-	subject := fmt.Sprintf("Order #%d Confirmation - Thank You for Your Purchase!", orderID)
-
-	totalAmountStr := strconv.FormatFloat(totalAmount, 'f', 2, 64)
-
-	bodyHTML := fmt.Sprintf(`
-        <html>
-        <body>
-            <p>Dear %s,</p>
-            <p>Thank you for your order! Your order #%d has been successfully placed.</p>
-            <p><strong>Order Details:</strong></p>
-            <ul>
-                <li>Order ID: %d</li>
-                <li>Total Amount: KES %s</li>
-            </ul>
-            <p>We'll send you another email when your order ships.</p>
-            <p>Best regards,</p>
-            <p>Your E-commerce Team</p>
-        </body>
-        </html>`, customerName, orderID, orderID, totalAmountStr)
-
-	bodyText := fmt.Sprintf(
-		"Dear %s,\n\nThank you for your order! Your order #%d has been successfully placed.\n\n"+
-			"Order Details:\nOrder ID: %d\nTotal Amount: KES %s\n\n"+ // Use totalAmountStr here
-			"We'll send you another email when your order ships.\n\nBest regards,\nYour E-commerce Team",
-		customerName, orderID, orderID, totalAmountStr)
+	if msg.UnsubscribeURL != "" {
+		return s.sendRawEmail(ctx, msg)
+	}
 
 	input := &ses.SendEmailInput{
-		Source: aws.String(cfg.SenderEmail),
+		Source: aws.String(s.senderEmail),
 		Destination: &types.Destination{
-			ToAddresses: []string{recipientEmail},
+			ToAddresses: []string{msg.To},
 		},
 		Message: &types.Message{
 			Subject: &types.Content{
 				Charset: aws.String("UTF-8"),
-				Data:    aws.String(subject),
+				Data:    aws.String(msg.Subject),
 			},
 			Body: &types.Body{
 				Html: &types.Content{
 					Charset: aws.String("UTF-8"),
-					Data:    aws.String(bodyHTML),
+					Data:    aws.String(msg.HTMLBody),
 				},
 				Text: &types.Content{
 					Charset: aws.String("UTF-8"),
-					Data:    aws.String(bodyText),
+					Data:    aws.String(msg.TextBody),
 				},
 			},
 		},
 	}
 
-	_, err = client.SendEmail(context.TODO(), input)
+	_, err := s.client.SendEmail(ctx, input)
+	if err != nil {
+		log.Printf("Failed to send email %q to %s: %v", msg.Subject, msg.To, err)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("Email %q sent successfully to %s", msg.Subject, msg.To)
+	return nil
+}
+
+// sendRawEmail sends msg as a MIME message built like the SMTP sender's,
+// since SES only passes headers such as List-Unsubscribe through raw email.
+func (s *SESSender) sendRawEmail(ctx context.Context, msg EmailMessage) error {
+	from, err := mail.ParseAddress(s.senderEmail)
+	if err != nil {
+		return fmt.Errorf("invalid sender email address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+
+	raw, err := buildMIMEMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.SendRawEmail(ctx, &ses.SendRawEmailInput{
+		Source:       aws.String(s.senderEmail),
+		Destinations: []string{msg.To},
+		RawMessage:   &types.RawMessage{Data: raw},
+	})
 	if err != nil {
-		log.Printf("Failed to send email for order %d to %s: %v", orderID, recipientEmail, err)
+		log.Printf("Failed to send email %q to %s: %v", msg.Subject, msg.To, err)
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	log.Printf("Order confirmation email sent successfully for order %d to %s", orderID, recipientEmail)
+	log.Printf("Email %q sent successfully to %s", msg.Subject, msg.To)
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,20 @@
+package notifier
+
+import (
+	"context"
+	"log"
+)
+
+// LogSender writes messages to the log instead of sending them. It is meant
+// for local development.
+type LogSender struct{}
+
+func (LogSender) SendSMS(ctx context.Context, msg SMSMessage) (SMSResult, error) {
+	log.Printf("[sms] to=%s body=%q", msg.To, msg.Body)
+	return SMSResult{Provider: ProviderLog, Status: "Logged"}, nil
+}
+
+func (LogSender) SendEmail(ctx context.Context, msg EmailMessage) error {
+	log.Printf("[email] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.TextBody)
+	return nil
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RecordingSender keeps every message in memory so tests can assert on
+// what would have been sent. Failures can be injected through SMSError and
+// EmailError. It is safe for concurrent use.
+type RecordingSender struct {
+	mu         sync.Mutex
+	sms        []SMSMessage
+	emails     []EmailMessage
+	SMSError   error
+	EmailError error
+}
+
+func NewRecordingSender() *RecordingSender {
+	return &RecordingSender{}
+}
+
+// SendSMS records msg and reports it as sent with a sequential message ID,
+// "memory-1", "memory-2" and so on.
+func (r *RecordingSender) SendSMS(ctx context.Context, msg SMSMessage) (SMSResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SMSError != nil {
+		return SMSResult{}, r.SMSError
+	}
+	r.sms = append(r.sms, msg)
+	return SMSResult{
+		Provider:  ProviderMemory,
+		MessageID: fmt.Sprintf("memory-%d", len(r.sms)),
+		Status:    SMSStatusSent,
+	}, nil
+}
+
+func (r *RecordingSender) SendEmail(ctx context.Context, msg EmailMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.EmailError != nil {
+		return r.EmailError
+	}
+	r.emails = append(r.emails, msg)
+	return nil
+}
+
+// SMS returns a copy of the text messages recorded so far.
+func (r *RecordingSender) SMS() []SMSMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SMSMessage(nil), r.sms...)
+}
+
+// Emails returns a copy of the emails recorded so far.
+func (r *RecordingSender) Emails() []EmailMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]EmailMessage(nil), r.emails...)
+}
+
+// Reset forgets everything recorded so far.
+func (r *RecordingSender) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sms = nil
+	r.emails = nil
+}
@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+)
+
+// SMSMessage is a single text message to one phone number.
+type SMSMessage struct {
+	To   string
+	Body string
+}
+
+// EmailMessage is a single email with both HTML and plain-text bodies.
+// UnsubscribeURL, when set, is sent as the List-Unsubscribe header with
+// List-Unsubscribe-Post, so mail clients can offer RFC 8058 one-click
+// unsubscribe by POSTing to it.
+type EmailMessage struct {
+	To             string
+	Subject        string
+	HTMLBody       string
+	TextBody       string
+	UnsubscribeURL string
+}
+
+// SMSStatusSent is the status of a text message the provider has accepted
+// but not yet reported as delivered.
+const SMSStatusSent = "Sent"
+
+// SMSResult is what the provider reported when it accepted (or rejected)
+// a text message. MessageID is the provider's reference, which later
+// delivery reports are keyed on; Cost is kept verbatim, e.g. "KES 0.8000".
+type SMSResult struct {
+	Provider   string
+	MessageID  string
+	Cost       string
+	Status     string
+	StatusCode int
+}
+
+// SMSSender delivers text messages through some provider.
+type SMSSender interface {
+	SendSMS(ctx context.Context, msg SMSMessage) (SMSResult, error)
+}
+
+// EmailSender delivers email through some provider.
+type EmailSender interface {
+	SendEmail(ctx context.Context, msg EmailMessage) error
+}
+
+// Provider names accepted by NewSMSSender and NewEmailSender.
+const (
+	ProviderAfricasTalking = "africastalking"
+	ProviderSES            = "ses"
+	ProviderSMTP           = "smtp"
+	ProviderLog            = "log"
+	ProviderMemory         = "memory"
+)
+
+// NewSMSSender builds the SMS provider named in cfg.
+func NewSMSSender(cfg config.NotifierConfig) (SMSSender, error) {
+	switch cfg.SMSProvider {
+	case ProviderAfricasTalking:
+		return NewAfricasTalkingSender(config.LoadAfricaTalkingConfig()), nil
+	case ProviderLog:
+		return LogSender{}, nil
+	case ProviderMemory:
+		return NewRecordingSender(), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMSProvider)
+	}
+}
+
+// NewEmailSender builds the email provider named in cfg.
+func NewEmailSender(cfg config.NotifierConfig) (EmailSender, error) {
+	switch cfg.EmailProvider {
+	case ProviderSES:
+		return NewSESSender(context.Background(), config.LoadEmailConfig())
+	case ProviderSMTP:
+		return NewSMTPSender(config.LoadSMTPConfig())
+	case ProviderLog:
+		return LogSender{}, nil
+	case ProviderMemory:
+		return NewRecordingSender(), nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.EmailProvider)
+	}
+}
@@ -1,6 +1,7 @@
 package notifier
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -24,34 +25,44 @@ type SMSResponse struct {
 	} `json:"SMSMessageData"`
 }
 
-func SendSMS(toPhoneNumber string, orderID uint, totalAmount float64) error {
+// AfricasTalkingSender sends SMS through the Africa's Talking messaging API.
+type AfricasTalkingSender struct {
+	cfg    config.AfricaTalkingConfig
+	client *http.Client
+}
+
+func NewAfricasTalkingSender(cfg config.AfricaTalkingConfig) *AfricasTalkingSender {
+	return &AfricasTalkingSender{cfg: cfg, client: &http.Client{}}
+}
 
-	cfg := config.LoadAfricaTalkingConfig()
+// Recipient status codes Africa's Talking uses for a message it accepted:
+// Processed, Sent and Queued. Anything else, e.g. 403 InvalidPhoneNumber,
+// means the message will not be delivered.
+var atAcceptedStatusCodes = map[int]bool{100: true, 101: true, 102: true}
 
-	message := fmt.Sprintf("Your order #%d has been successfully placed! Total: KES %.2f. Thank you for shopping with us!", orderID, totalAmount)
+func (s *AfricasTalkingSender) SendSMS(ctx context.Context, msg SMSMessage) (SMSResult, error) {
 
 	data := url.Values{}
-	data.Set("username", cfg.Username)
-	data.Set("to", toPhoneNumber)
-	data.Set("message", message)
-	data.Set("from", cfg.SenderID)
+	data.Set("username", s.cfg.Username)
+	data.Set("to", msg.To)
+	data.Set("message", msg.Body)
+	data.Set("from", s.cfg.SenderID)
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", cfg.SMSURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.SMSURL, strings.NewReader(data.Encode()))
 
 	if err != nil {
-		return fmt.Errorf("failed to create SMS request: %w", err)
+		return SMSResult{}, fmt.Errorf("failed to create SMS request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("apikey", cfg.APIKey)
+	req.Header.Set("apikey", s.cfg.APIKey)
 
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 
 	if err != nil {
-		log.Printf("SMS send failed to %s for order %d: %v\n", toPhoneNumber, orderID, err)
-		return fmt.Errorf("SMS send failed: %w", err)
+		log.Printf("SMS send failed to %s: %v\n", msg.To, err)
+		return SMSResult{}, fmt.Errorf("SMS send failed: %w", err)
 	}
 
 	defer resp.Body.Close()
@@ -59,19 +70,40 @@ func SendSMS(toPhoneNumber string, orderID uint, totalAmount float64) error {
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		var smsResp SMSResponse
 		if decodeErr := json.NewDecoder(resp.Body).Decode(&smsResp); decodeErr == nil {
-			log.Printf("SMS API returned error for %s (order %d): Status %d, Message: %s\n", toPhoneNumber, orderID, resp.StatusCode, smsResp.SMSMessageData.Message)
+			log.Printf("SMS API returned error for %s: Status %d, Message: %s\n", msg.To, resp.StatusCode, smsResp.SMSMessageData.Message)
 		} else {
-			log.Printf("SMS API returned non-success status %d for %s (order %d) and failed to decode response: %v\n", resp.StatusCode, toPhoneNumber, orderID, decodeErr)
+			log.Printf("SMS API returned non-success status %d for %s and failed to decode response: %v\n", resp.StatusCode, msg.To, decodeErr)
 		}
-		return fmt.Errorf("SMS API returned non-success status: %d", resp.StatusCode)
+		return SMSResult{}, fmt.Errorf("SMS API returned non-success status: %d", resp.StatusCode)
 	}
 
 	var smsResp SMSResponse
 	if err := json.NewDecoder(resp.Body).Decode(&smsResp); err != nil {
-		log.Printf("Failed to decode SMS response for %s (order %d): %v\n", toPhoneNumber, orderID, err)
-		return fmt.Errorf("failed to decode SMS response: %w", err)
+		log.Printf("Failed to decode SMS response for %s: %v\n", msg.To, err)
+		return SMSResult{}, fmt.Errorf("failed to decode SMS response: %w", err)
 	}
 
-	log.Printf("SMS sent successfully to %s for order %d. Message: %s\n", toPhoneNumber, orderID, smsResp.SMSMessageData.Message)
-	return nil
-}
\ No newline at end of file
+	if len(smsResp.SMSMessageData.Recipients) == 0 {
+		return SMSResult{}, fmt.Errorf("SMS API accepted no recipients: %s", smsResp.SMSMessageData.Message)
+	}
+
+	recipient := smsResp.SMSMessageData.Recipients[0]
+	result := SMSResult{
+		Provider:   ProviderAfricasTalking,
+		MessageID:  recipient.MessageID,
+		Cost:       recipient.Cost,
+		Status:     recipient.Status,
+		StatusCode: recipient.StatusCode,
+	}
+
+	if !atAcceptedStatusCodes[recipient.StatusCode] {
+		return result, fmt.Errorf("SMS to %s rejected: %s (%d)", msg.To, recipient.Status, recipient.StatusCode)
+	}
+
+	// The API answers "Success" when it accepts a message, which is not the
+	// same as the handset's "Success" delivery report that comes later.
+	result.Status = SMSStatusSent
+
+	log.Printf("SMS sent successfully to %s. Message: %s, ID: %s\n", msg.To, smsResp.SMSMessageData.Message, recipient.MessageID)
+	return result, nil
+}
@@ -0,0 +1,311 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+)
+
+// TLS modes and auth mechanisms accepted in config.SMTPConfig.
+const (
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+	SMTPTLSNone     = "none"
+
+	SMTPAuthPlain = "plain"
+	SMTPAuthLogin = "login"
+	SMTPAuthNone  = "none"
+)
+
+// smtpTimeout bounds a delivery when the caller's context has no deadline
+// of its own.
+const smtpTimeout = 30 * time.Second
+
+// SMTPSender sends email through an SMTP relay, as a multipart/alternative
+// message carrying both the text and HTML bodies.
+type SMTPSender struct {
+	cfg config.SMTPConfig
+
+	// TLSConfig is used for STARTTLS and implicit TLS. When nil the relay's
+	// certificate is verified against the system roots for cfg.Host.
+	TLSConfig *tls.Config
+}
+
+func NewSMTPSender(cfg config.SMTPConfig) (*SMTPSender, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP host is not configured")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid SMTP from address %q: %w", cfg.From, err)
+	}
+
+	switch cfg.TLSMode {
+	case SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", cfg.TLSMode)
+	}
+
+	switch cfg.AuthMechanism {
+	case SMTPAuthPlain, SMTPAuthLogin, SMTPAuthNone, "":
+	default:
+		return nil, fmt.Errorf("unknown SMTP auth mechanism %q", cfg.AuthMechanism)
+	}
+
+	return &SMTPSender{cfg: cfg}, nil
+}
+
+func (s *SMTPSender) SendEmail(ctx context.Context, msg EmailMessage) error {
+	if msg.To == "" {
+		return fmt.Errorf("recipient email address is empty")
+	}
+
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+
+	body, err := buildMIMEMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := s.authenticate(client); err != nil {
+		return err
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP RCPT TO: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the relay and, depending on TLSMode, wraps the
+// connection in TLS straight away or upgrades it with STARTTLS. The
+// connection is bound to ctx's deadline.
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to SMTP relay %s: %w", addr, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(smtpTimeout))
+	}
+
+	if s.cfg.TLSMode == SMTPTLSImplicit {
+		tlsConn := tls.Client(conn, s.tlsConfig())
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s: %w", addr, err)
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP greeting from %s: %w", addr, err)
+	}
+
+	if s.cfg.TLSMode == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP relay %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+func (s *SMTPSender) authenticate(client *smtp.Client) error {
+	var auth smtp.Auth
+	switch s.cfg.AuthMechanism {
+	case SMTPAuthPlain:
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	case SMTPAuthLogin:
+		auth = &loginAuth{username: s.cfg.Username, password: s.cfg.Password, host: s.cfg.Host}
+	default:
+		return nil
+	}
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("SMTP auth: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	if s.TLSConfig != nil {
+		return s.TLSConfig
+	}
+	return &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not
+// provide. Like smtp.PlainAuth it refuses to send credentials over an
+// unencrypted connection to anything but localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// buildMIMEMessage renders msg as a multipart/alternative message with a
+// quoted-printable text part followed by the HTML part, so clients that can
+// show HTML prefer it.
+func buildMIMEMessage(from, to *mail.Address, msg EmailMessage) ([]byte, error) {
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	fields := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+	}
+	if msg.UnsubscribeURL != "" {
+		fields = append(fields,
+			[2]string{"List-Unsubscribe", "<" + msg.UnsubscribeURL + ">"},
+			[2]string{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		)
+	}
+	fields = append(fields,
+		[2]string{"MIME-Version", "1.0"},
+		[2]string{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	)
+
+	var buf bytes.Buffer
+	for _, field := range fields {
+		fmt.Fprintf(&buf, "%s: %s\r\n", field[0], field[1])
+	}
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.TextBody},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	} {
+		if part.content == "" {
+			continue
+		}
+
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func newMessageID(fromAddress string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 {
+		domain = fromAddress[at+1:]
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain), nil
+}
@@ -0,0 +1,205 @@
+package notifier
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// FallbackLocale is used when neither the customer's locale nor the
+// configured default has a template.
+const FallbackLocale = "en"
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// OrderLine is one line item as shown in a notification.
+type OrderLine struct {
+	Name      string
+	Quantity  uint
+	UnitPrice models.Money
+	LineTotal models.Money
+}
+
+// OrderPlacedData is what the order_placed templates are rendered with.
+type OrderPlacedData struct {
+	CustomerName string
+	OrderID      uint
+	Items        []OrderLine
+	Total        models.Money
+	// UnsubscribeURL, when set, is shown at the foot of emails.
+	UnsubscribeURL string
+}
+
+// Templates renders notification content per locale. Templates live in
+// <locale>/<name>.tmpl, e.g. sw/order_placed.sms.tmpl. Files ending in
+// .html.tmpl are parsed with html/template so values are escaped; all
+// others use text/template.
+type Templates struct {
+	defaultLocale string
+	text          map[string]map[string]*texttemplate.Template
+	html          map[string]map[string]*htmltemplate.Template
+}
+
+// DefaultTemplates returns the built-in English and Swahili templates.
+func DefaultTemplates() *Templates {
+	templates, err := LoadTemplates("", FallbackLocale)
+	if err != nil {
+		panic(fmt.Sprintf("built-in notification templates: %v", err))
+	}
+	return templates
+}
+
+// LoadTemplates parses the built-in templates and then any found in
+// overrideDir, which replace built-in files of the same locale and name and
+// may add new locales. defaultLocale is tried when a customer's own locale
+// has no template.
+func LoadTemplates(overrideDir string, defaultLocale string) (*Templates, error) {
+	t := &Templates{
+		defaultLocale: normalizeLocale(defaultLocale),
+		text:          make(map[string]map[string]*texttemplate.Template),
+		html:          make(map[string]map[string]*htmltemplate.Template),
+	}
+
+	builtIn, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.parseDir(builtIn); err != nil {
+		return nil, err
+	}
+
+	if overrideDir != "" {
+		if err := t.parseDir(os.DirFS(overrideDir)); err != nil {
+			return nil, fmt.Errorf("loading templates from %s: %w", overrideDir, err)
+		}
+	}
+
+	return t, nil
+}
+
+func (t *Templates) parseDir(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(filePath, ".tmpl") {
+			return nil
+		}
+
+		locale, file := path.Split(filePath)
+		locale = normalizeLocale(strings.TrimSuffix(locale, "/"))
+		if locale == "" || strings.Contains(locale, "/") {
+			return nil
+		}
+
+		source, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(file, ".tmpl")
+		if strings.HasSuffix(name, ".html") {
+			parsed, err := htmltemplate.New(name).Parse(string(source))
+			if err != nil {
+				return err
+			}
+			if t.html[locale] == nil {
+				t.html[locale] = make(map[string]*htmltemplate.Template)
+			}
+			t.html[locale][name] = parsed
+			return nil
+		}
+
+		parsed, err := texttemplate.New(name).Parse(string(source))
+		if err != nil {
+			return err
+		}
+		if t.text[locale] == nil {
+			t.text[locale] = make(map[string]*texttemplate.Template)
+		}
+		t.text[locale][name] = parsed
+		return nil
+	})
+}
+
+// OrderPlacedSMS renders the order confirmation text message in locale.
+func (t *Templates) OrderPlacedSMS(toPhoneNumber string, locale string, data OrderPlacedData) (SMSMessage, error) {
+	body, err := t.render(locale, "order_placed.sms", data)
+	if err != nil {
+		return SMSMessage{}, err
+	}
+
+	return SMSMessage{To: toPhoneNumber, Body: strings.TrimSpace(body)}, nil
+}
+
+// OrderPlacedEmail renders the order confirmation email in locale.
+func (t *Templates) OrderPlacedEmail(recipientEmail string, locale string, data OrderPlacedData) (EmailMessage, error) {
+	subject, err := t.render(locale, "order_placed.subject", data)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+
+	bodyText, err := t.render(locale, "order_placed.txt", data)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+
+	bodyHTML, err := t.render(locale, "order_placed.html", data)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+
+	return EmailMessage{
+		To:             recipientEmail,
+		Subject:        strings.TrimSpace(subject),
+		HTMLBody:       bodyHTML,
+		TextBody:       bodyText,
+		UnsubscribeURL: data.UnsubscribeURL,
+	}, nil
+}
+
+// render executes the named template for the first locale that has it: the
+// requested locale, its base language (sw for sw-KE), the default locale and
+// finally FallbackLocale.
+func (t *Templates) render(locale string, name string, data interface{}) (string, error) {
+	for _, candidate := range t.localeChain(locale) {
+		var out bytes.Buffer
+
+		if tmpl, ok := t.html[candidate][name]; ok {
+			if err := tmpl.Execute(&out, data); err != nil {
+				return "", err
+			}
+			return out.String(), nil
+		}
+
+		if tmpl, ok := t.text[candidate][name]; ok {
+			if err := tmpl.Execute(&out, data); err != nil {
+				return "", err
+			}
+			return out.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s template for locale %q", name, locale)
+}
+
+func (t *Templates) localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	base, _, _ := strings.Cut(locale, "-")
+	return []string{locale, base, t.defaultLocale, FallbackLocale}
+}
+
+// normalizeLocale lower-cases a locale and uses a hyphen as separator, so
+// "sw_KE" and "sw-ke" both become "sw-ke".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,119 @@
+package notifier_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+)
+
+func TestAfricasTalkingSender(t *testing.T) {
+	t.Run("Posts the message to the configured endpoint", func(t *testing.T) {
+		var received url.Values
+		var apiKey string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			received = r.PostForm
+			apiKey = r.Header.Get("apikey")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"SMSMessageData": {"Message": "Sent to 1/1 Total Cost: KES 0.8000", "Recipients": [{"statusCode": 101, "number": "+254700000000", "cost": "KES 0.8000", "status": "Success", "messageId": "ATXid_1"}]}}`))
+		}))
+		defer server.Close()
+
+		sender := notifier.NewAfricasTalkingSender(config.AfricaTalkingConfig{
+			Username: "sandbox",
+			APIKey:   "secret",
+			SMSURL:   server.URL,
+			SenderID: "SHOP",
+		})
+
+		result, err := sender.SendSMS(context.Background(), notifier.SMSMessage{To: "+254700000000", Body: "hello"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, notifier.SMSResult{Provider: "africastalking", MessageID: "ATXid_1", Cost: "KES 0.8000", Status: "Sent", StatusCode: 101}, result)
+		assert.Equal(t, "secret", apiKey)
+		assert.Equal(t, "sandbox", received.Get("username"))
+		assert.Equal(t, "+254700000000", received.Get("to"))
+		assert.Equal(t, "hello", received.Get("message"))
+		assert.Equal(t, "SHOP", received.Get("from"))
+	})
+
+	t.Run("Returns an error for a non-success status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"SMSMessageData": {"Message": "Invalid API key"}}`))
+		}))
+		defer server.Close()
+
+		sender := notifier.NewAfricasTalkingSender(config.AfricaTalkingConfig{SMSURL: server.URL})
+
+		_, err := sender.SendSMS(context.Background(), notifier.SMSMessage{To: "+254700000000", Body: "hello"})
+
+		assert.EqualError(t, err, "SMS API returned non-success status: 401")
+	})
+
+	t.Run("Returns the result and an error for a rejected recipient", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"SMSMessageData": {"Message": "Sent to 0/1", "Recipients": [{"statusCode": 403, "number": "+2547", "cost": "0", "status": "InvalidPhoneNumber", "messageId": "None"}]}}`))
+		}))
+		defer server.Close()
+
+		sender := notifier.NewAfricasTalkingSender(config.AfricaTalkingConfig{SMSURL: server.URL})
+
+		result, err := sender.SendSMS(context.Background(), notifier.SMSMessage{To: "+2547", Body: "hello"})
+
+		assert.EqualError(t, err, "SMS to +2547 rejected: InvalidPhoneNumber (403)")
+		assert.Equal(t, "InvalidPhoneNumber", result.Status)
+		assert.Equal(t, 403, result.StatusCode)
+	})
+}
+
+func TestRecordingSender(t *testing.T) {
+	recorder := notifier.NewRecordingSender()
+
+	result, err := recorder.SendSMS(context.Background(), notifier.SMSMessage{To: "+254700000000", Body: "hi"})
+	assert.NoError(t, err)
+	assert.Equal(t, "memory-1", result.MessageID)
+	assert.NoError(t, recorder.SendEmail(context.Background(), notifier.EmailMessage{To: "a@example.com", Subject: "hi"}))
+	assert.Len(t, recorder.SMS(), 1)
+	assert.Len(t, recorder.Emails(), 1)
+
+	recorder.SMSError = errors.New("provider down")
+	_, err = recorder.SendSMS(context.Background(), notifier.SMSMessage{})
+	assert.EqualError(t, err, "provider down")
+	assert.Len(t, recorder.SMS(), 1)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.SMS())
+	assert.Empty(t, recorder.Emails())
+}
+
+func TestSenderFactories(t *testing.T) {
+	sms, err := notifier.NewSMSSender(config.NotifierConfig{SMSProvider: notifier.ProviderLog})
+	assert.NoError(t, err)
+	assert.IsType(t, notifier.LogSender{}, sms)
+
+	email, err := notifier.NewEmailSender(config.NotifierConfig{EmailProvider: notifier.ProviderMemory})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.RecordingSender{}, email)
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_FROM", "orders@shop.example")
+	email, err = notifier.NewEmailSender(config.NotifierConfig{EmailProvider: notifier.ProviderSMTP})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.SMTPSender{}, email)
+
+	_, err = notifier.NewSMSSender(config.NotifierConfig{SMSProvider: "pigeon"})
+	assert.Error(t, err)
+
+	_, err = notifier.NewEmailSender(config.NotifierConfig{EmailProvider: "pigeon"})
+	assert.Error(t, err)
+}
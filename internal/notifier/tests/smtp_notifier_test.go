@@ -0,0 +1,196 @@
+package notifier_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+)
+
+func newTestSMTPSender(t *testing.T, server *fakeSMTPServer, tlsMode, authMechanism, password string) *notifier.SMTPSender {
+	t.Helper()
+
+	sender, err := notifier.NewSMTPSender(config.SMTPConfig{
+		Host:          "127.0.0.1",
+		Port:          server.Port(),
+		Username:      "relay-user",
+		Password:      password,
+		From:          "Shop <orders@shop.example>",
+		TLSMode:       tlsMode,
+		AuthMechanism: authMechanism,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+	sender.TLSConfig = server.ClientTLSConfig()
+
+	return sender
+}
+
+var testEmail = notifier.EmailMessage{
+	To:       "jane@example.com",
+	Subject:  "Oda #42 – Asante!",
+	TextBody: "Dear Jane,\nYour order is placed.",
+	HTMLBody: "<p>Dear Jane,</p><p>Your order is placed.</p>",
+}
+
+func TestSMTPSender(t *testing.T) {
+	cases := []struct {
+		name     string
+		implicit bool
+		tlsMode  string
+		auth     string
+	}{
+		{"STARTTLS with PLAIN auth", false, notifier.SMTPTLSStartTLS, notifier.SMTPAuthPlain},
+		{"STARTTLS with LOGIN auth", false, notifier.SMTPTLSStartTLS, notifier.SMTPAuthLogin},
+		{"Implicit TLS with PLAIN auth", true, notifier.SMTPTLSImplicit, notifier.SMTPAuthPlain},
+		{"Implicit TLS with LOGIN auth", true, notifier.SMTPTLSImplicit, notifier.SMTPAuthLogin},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, tc.implicit)
+			sender := newTestSMTPSender(t, server, tc.tlsMode, tc.auth, "relay-pass")
+
+			err := sender.SendEmail(context.Background(), testEmail)
+			assert.NoError(t, err)
+
+			messages := server.Messages()
+			if !assert.Len(t, messages, 1) {
+				return
+			}
+
+			received := messages[0]
+			assert.True(t, received.TLS)
+			assert.Equal(t, strings.ToUpper(tc.auth), received.AuthMech)
+			assert.Equal(t, "relay-user", received.AuthUser)
+			assert.Equal(t, "orders@shop.example", received.From)
+			assert.Equal(t, []string{"jane@example.com"}, received.To)
+		})
+	}
+}
+
+func TestSMTPSenderBuildsMultipartMessage(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	sender := newTestSMTPSender(t, server, notifier.SMTPTLSStartTLS, notifier.SMTPAuthPlain, "relay-pass")
+
+	assert.NoError(t, sender.SendEmail(context.Background(), testEmail))
+
+	messages := server.Messages()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, testEmail.Subject, subject)
+	assert.Equal(t, `"Shop" <orders@shop.example>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<jane@example.com>", parsed.Header.Get("To"))
+	assert.NotEmpty(t, parsed.Header.Get("Message-ID"))
+	assert.Empty(t, parsed.Header.Get("List-Unsubscribe"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies = map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		// multipart.Reader transparently decodes quoted-printable parts.
+		content, _ := io.ReadAll(part)
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[partType] = string(content)
+	}
+
+	assert.Equal(t, testEmail.TextBody, bodies["text/plain"])
+	assert.Equal(t, testEmail.HTMLBody, bodies["text/html"])
+}
+
+func TestSMTPSenderAddsOneClickUnsubscribeHeaders(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	sender := newTestSMTPSender(t, server, notifier.SMTPTLSStartTLS, notifier.SMTPAuthPlain, "relay-pass")
+
+	email := testEmail
+	email.UnsubscribeURL = "https://shop.example.com/unsubscribe?token=abc"
+	assert.NoError(t, sender.SendEmail(context.Background(), email))
+
+	messages := server.Messages()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "<https://shop.example.com/unsubscribe?token=abc>", parsed.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", parsed.Header.Get("List-Unsubscribe-Post"))
+}
+
+func TestSMTPSenderRejectsBadCredentials(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	sender := newTestSMTPSender(t, server, notifier.SMTPTLSStartTLS, notifier.SMTPAuthLogin, "wrong")
+
+	err := sender.SendEmail(context.Background(), testEmail)
+
+	assert.ErrorContains(t, err, "SMTP auth")
+	assert.Empty(t, server.Messages())
+}
+
+func TestSMTPSenderRejectsUntrustedCertificate(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+	sender := newTestSMTPSender(t, server, notifier.SMTPTLSImplicit, notifier.SMTPAuthPlain, "relay-pass")
+	sender.TLSConfig = nil
+
+	err := sender.SendEmail(context.Background(), testEmail)
+
+	assert.ErrorContains(t, err, "TLS handshake")
+	assert.Empty(t, server.Messages())
+}
+
+func TestNewSMTPSenderValidatesConfig(t *testing.T) {
+	valid := config.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "orders@shop.example", TLSMode: "starttls", AuthMechanism: "plain"}
+
+	_, err := notifier.NewSMTPSender(valid)
+	assert.NoError(t, err)
+
+	missingHost := valid
+	missingHost.Host = ""
+	_, err = notifier.NewSMTPSender(missingHost)
+	assert.Error(t, err)
+
+	badFrom := valid
+	badFrom.From = "not an address"
+	_, err = notifier.NewSMTPSender(badFrom)
+	assert.Error(t, err)
+
+	badTLS := valid
+	badTLS.TLSMode = "ssl3"
+	_, err = notifier.NewSMTPSender(badTLS)
+	assert.Error(t, err)
+
+	badAuth := valid
+	badAuth.AuthMechanism = "cram-md5"
+	_, err = notifier.NewSMTPSender(badAuth)
+	assert.Error(t, err)
+}
@@ -0,0 +1,267 @@
+package notifier_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedMail is one message accepted by fakeSMTPServer.
+type receivedMail struct {
+	From     string
+	To       []string
+	Data     string
+	AuthMech string
+	AuthUser string
+	TLS      bool
+}
+
+// fakeSMTPServer is a minimal in-process SMTP server: enough of RFC 5321
+// plus STARTTLS, implicit TLS and AUTH PLAIN/LOGIN to exercise SMTPSender.
+type fakeSMTPServer struct {
+	t        *testing.T
+	listener net.Listener
+	tls      *tls.Config
+	implicit bool
+	username string
+	password string
+
+	mu       sync.Mutex
+	messages []receivedMail
+}
+
+func newFakeSMTPServer(t *testing.T, implicitTLS bool) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := &fakeSMTPServer{
+		t:        t,
+		listener: listener,
+		tls:      &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}},
+		implicit: implicitTLS,
+		username: "relay-user",
+		password: "relay-pass",
+	}
+
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return server
+}
+
+func (s *fakeSMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// ClientTLSConfig trusts the server's self-signed certificate.
+func (s *fakeSMTPServer) ClientTLSConfig() *tls.Config {
+	leaf, _ := x509.ParseCertificate(s.tls.Certificates[0].Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func (s *fakeSMTPServer) Messages() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]receivedMail(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	secure := false
+	if s.implicit {
+		tlsConn := tls.Server(conn, s.tls)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		conn = tlsConn
+		secure = true
+	}
+
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) { text.PrintfLine(format, args...) }
+
+	var current receivedMail
+	authenticated := false
+
+	reply("220 fake.smtp ESMTP ready")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-fake.smtp")
+			if !secure && !s.implicit {
+				reply("250-STARTTLS")
+			}
+			reply("250-AUTH PLAIN LOGIN")
+			reply("250 8BITMIME")
+
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			secure = true
+
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			user, pass, ok := s.readCredentials(text, strings.ToUpper(mech), initial)
+			if !ok || user != s.username || pass != s.password {
+				reply("535 5.7.8 Authentication credentials invalid")
+				continue
+			}
+			authenticated = true
+			current.AuthMech = strings.ToUpper(mech)
+			current.AuthUser = user
+			reply("235 2.7.0 Authentication successful")
+
+		case "MAIL":
+			if !authenticated {
+				reply("530 5.7.0 Authentication required")
+				continue
+			}
+			current.From = smtpPath(arg)
+			reply("250 OK")
+
+		case "RCPT":
+			current.To = append(current.To, smtpPath(arg))
+			reply("250 OK")
+
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			current.TLS = secure
+
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+
+			current = receivedMail{AuthMech: current.AuthMech, AuthUser: current.AuthUser}
+			reply("250 OK: queued")
+
+		case "RSET", "NOOP":
+			reply("250 OK")
+
+		case "QUIT":
+			reply("221 Bye")
+			return
+
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) readCredentials(text *textproto.Conn, mech, initial string) (string, string, bool) {
+	decode := func(value string) string {
+		decoded, _ := base64.StdEncoding.DecodeString(value)
+		return string(decoded)
+	}
+	prompt := func(challenge string) (string, bool) {
+		text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, err := text.ReadLine()
+		return decode(line), err == nil
+	}
+
+	switch mech {
+	case "PLAIN":
+		response := decode(initial)
+		if initial == "" {
+			var ok bool
+			if response, ok = prompt(""); !ok {
+				return "", "", false
+			}
+		}
+		fields := strings.Split(response, "\x00")
+		if len(fields) != 3 {
+			return "", "", false
+		}
+		return fields[1], fields[2], true
+
+	case "LOGIN":
+		user, ok := prompt("Username:")
+		if !ok {
+			return "", "", false
+		}
+		pass, ok := prompt("Password:")
+		return user, pass, ok
+
+	default:
+		return "", "", false
+	}
+}
+
+// smtpPath extracts the address from a MAIL FROM:<...> or RCPT TO:<...>
+// argument, ignoring any parameters such as BODY=8BITMIME.
+func smtpPath(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	address, _, _ := strings.Cut(rest, ">")
+	return address
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake.smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,128 @@
+package notifier_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+)
+
+func orderPlacedFixture() notifier.OrderPlacedData {
+	return notifier.OrderPlacedData{
+		CustomerName: "Jane <Wanjiru>",
+		OrderID:      42,
+		Items: []notifier.OrderLine{
+			{Name: "Chair", Quantity: 2, UnitPrice: models.NewMoney(50000, "KES"), LineTotal: models.NewMoney(100000, "KES")},
+			{Name: "Table", Quantity: 1, UnitPrice: models.NewMoney(23450, "KES"), LineTotal: models.NewMoney(23450, "KES")},
+		},
+		Total: models.NewMoney(123450, "KES"),
+	}
+}
+
+func TestOrderPlacedTemplates(t *testing.T) {
+	templates := notifier.DefaultTemplates()
+	data := orderPlacedFixture()
+
+	t.Run("Renders English with line items", func(t *testing.T) {
+		sms, err := templates.OrderPlacedSMS("+254700000000", "en", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "+254700000000", sms.To)
+		assert.Equal(t, "Your order #42 has been successfully placed: 2 x Chair, 1 x Table. Total: KES 1234.50. Thank you for shopping with us!", sms.Body)
+
+		email, err := templates.OrderPlacedEmail("jane@example.com", "en", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", email.To)
+		assert.Equal(t, "Order #42 Confirmation - Thank You for Your Purchase!", email.Subject)
+		assert.Contains(t, email.TextBody, "Dear Jane <Wanjiru>,")
+		assert.Contains(t, email.TextBody, "2 x Chair @ KES 500.00 = KES 1000.00")
+		assert.Contains(t, email.TextBody, "Total Amount: KES 1234.50")
+		assert.Contains(t, email.HTMLBody, "<td>Table</td><td>1</td><td>KES 234.50</td><td>KES 234.50</td>")
+	})
+
+	t.Run("Escapes values in the HTML body only", func(t *testing.T) {
+		email, err := templates.OrderPlacedEmail("jane@example.com", "en", data)
+		assert.NoError(t, err)
+		assert.Contains(t, email.HTMLBody, "Dear Jane &lt;Wanjiru&gt;,")
+		assert.NotContains(t, email.HTMLBody, "<Wanjiru>")
+	})
+
+	t.Run("Renders Swahili, including for a regional tag", func(t *testing.T) {
+		for _, locale := range []string{"sw", "sw-KE", "sw_ke"} {
+			sms, err := templates.OrderPlacedSMS("+254700000000", locale, data)
+			assert.NoError(t, err)
+			assert.Equal(t, "Oda yako #42 imepokelewa: 2 x Chair, 1 x Table. Jumla: KES 1234.50. Asante kwa kununua nasi!", sms.Body)
+		}
+
+		email, err := templates.OrderPlacedEmail("jane@example.com", "sw-KE", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Uthibitisho wa Oda #42 - Asante kwa Ununuzi Wako!", email.Subject)
+		assert.Contains(t, email.TextBody, "Mpendwa Jane <Wanjiru>,")
+	})
+
+	t.Run("Falls back for unknown or missing locales", func(t *testing.T) {
+		for _, locale := range []string{"", "fr-FR"} {
+			sms, err := templates.OrderPlacedSMS("+254700000000", locale, data)
+			assert.NoError(t, err)
+			assert.Contains(t, sms.Body, "Your order #42")
+		}
+	})
+}
+
+func TestLoadTemplatesWithOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "en/order_placed.sms.tmpl", "Order {{.OrderID}} confirmed for {{.CustomerName}}.")
+	writeTemplate(t, dir, "fr/order_placed.sms.tmpl", "Commande {{.OrderID}} confirmée.")
+
+	data := orderPlacedFixture()
+
+	t.Run("Override replaces the built-in template and adds new locales", func(t *testing.T) {
+		templates, err := notifier.LoadTemplates(dir, "en")
+		assert.NoError(t, err)
+
+		sms, err := templates.OrderPlacedSMS("+254700000000", "en", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Order 42 confirmed for Jane <Wanjiru>.", sms.Body)
+
+		sms, err = templates.OrderPlacedSMS("+254700000000", "fr-CA", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Commande 42 confirmée.", sms.Body)
+
+		// Templates not overridden still come from the built-in set.
+		email, err := templates.OrderPlacedEmail("jane@example.com", "fr", data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Order #42 Confirmation - Thank You for Your Purchase!", email.Subject)
+	})
+
+	t.Run("Default locale is tried before the fallback", func(t *testing.T) {
+		templates, err := notifier.LoadTemplates("", "sw")
+		assert.NoError(t, err)
+
+		sms, err := templates.OrderPlacedSMS("+254700000000", "de", data)
+		assert.NoError(t, err)
+		assert.Contains(t, sms.Body, "Oda yako #42")
+	})
+
+	t.Run("Reports template syntax errors", func(t *testing.T) {
+		broken := t.TempDir()
+		writeTemplate(t, broken, "en/order_placed.sms.tmpl", "{{.OrderID")
+
+		_, err := notifier.LoadTemplates(broken, "en")
+		assert.Error(t, err)
+	})
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
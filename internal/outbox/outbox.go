@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+)
+
+// claimLease is how far a claimed message's NextAttemptAt is pushed ahead
+// while it is being delivered. It must comfortably exceed sendTimeout so a
+// slow delivery is never picked up twice.
+const (
+	claimLease  = 2 * time.Minute
+	sendTimeout = 30 * time.Second
+)
+
+// EnqueueSMS writes a text message to the outbox within tx, so it is only
+// delivered if the surrounding transaction commits.
+func EnqueueSMS(tx *gorm.DB, msg notifier.SMSMessage, orderID *uint) error {
+	return tx.Create(&models.OutboxMessage{
+		Channel:       models.OutboxChannelSMS,
+		Recipient:     msg.To,
+		Body:          msg.Body,
+		OrderID:       orderID,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// EnqueueEmail writes an email to the outbox within tx.
+func EnqueueEmail(tx *gorm.DB, msg notifier.EmailMessage, orderID *uint) error {
+	return tx.Create(&models.OutboxMessage{
+		Channel:        models.OutboxChannelEmail,
+		Recipient:      msg.To,
+		Subject:        msg.Subject,
+		Body:           msg.TextBody,
+		HTMLBody:       msg.HTMLBody,
+		UnsubscribeURL: msg.UnsubscribeURL,
+		OrderID:        orderID,
+		Status:         models.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	}).Error
+}
+
+// Worker delivers pending outbox messages. Failed deliveries are retried
+// with exponential backoff until MaxAttempts is reached, after which the
+// message is marked dead and left for an admin to re-drive.
+type Worker struct {
+	db    *gorm.DB
+	sms   notifier.SMSSender
+	email notifier.EmailSender
+	cfg   config.OutboxConfig
+
+	// Now reports the current time. Tests replace it to step through the
+	// backoff schedule without sleeping.
+	Now func() time.Time
+}
+
+func NewWorker(database *gorm.DB, sms notifier.SMSSender, email notifier.EmailSender, cfg config.OutboxConfig) *Worker {
+	return &Worker{db: database, sms: sms, email: email, cfg: cfg, Now: time.Now}
+}
+
+// Run polls for due messages until ctx is cancelled. A full batch is
+// followed immediately by another poll so a backlog drains quickly.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := w.ProcessDue(ctx)
+		if err != nil {
+			log.Printf("outbox: %v", err)
+		}
+
+		if err == nil && processed == w.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue claims up to BatchSize due messages, attempts each once and
+// records the outcome. It returns how many messages were attempted; a
+// message whose outcome could not be recorded does not hold up the rest,
+// and the errors are returned together.
+func (w *Worker) ProcessDue(ctx context.Context) (int, error) {
+	now := w.Now()
+
+	var claimed []models.OutboxMessage
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		err := db.ForUpdate(tx).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, now).
+			Order("next_attempt_at, id").
+			Limit(w.cfg.BatchSize).
+			Find(&claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(claimed))
+		for _, msg := range claimed {
+			ids = append(ids, msg.ID)
+		}
+
+		return tx.Model(&models.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(claimLease)).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("claiming messages: %w", err)
+	}
+
+	var errs []error
+	for _, msg := range claimed {
+		if err := w.deliver(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("recording delivery of message %d: %w", msg.ID, err))
+		}
+	}
+
+	return len(claimed), errors.Join(errs...)
+}
+
+// Backoff returns the delay before the next attempt once attempts
+// deliveries have failed.
+func (w *Worker) Backoff(attempts int) time.Duration {
+	delay := w.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < w.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > w.cfg.MaxBackoff {
+		delay = w.cfg.MaxBackoff
+	}
+
+	return delay
+}
+
+func (w *Worker) deliver(ctx context.Context, msg models.OutboxMessage) error {
+	result, sendErr := w.send(ctx, msg)
+	attempts := msg.Attempts + 1
+	now := w.Now()
+
+	updates := map[string]interface{}{"attempts": attempts}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.OutboxStatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case attempts >= w.cfg.MaxAttempts:
+		log.Printf("outbox: giving up on %s message %d to %s after %d attempts: %v", msg.Channel, msg.ID, msg.Recipient, attempts, sendErr)
+		updates["status"] = models.OutboxStatusDead
+		updates["last_error"] = sendErr.Error()
+	default:
+		log.Printf("outbox: %s message %d to %s failed (attempt %d): %v", msg.Channel, msg.ID, msg.Recipient, attempts, sendErr)
+		updates["next_attempt_at"] = now.Add(w.Backoff(attempts))
+		updates["last_error"] = sendErr.Error()
+	}
+
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		if err := recordSMSDelivery(tx, msg, result); err != nil {
+			return err
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id = ?", msg.ID).Updates(updates).Error
+	})
+}
+
+// recordSMSDelivery keeps whatever the SMS provider reported for an
+// attempt, including rejections, so later delivery reports can be matched
+// by message ID. Attempts that never reached the provider leave no record.
+func recordSMSDelivery(tx *gorm.DB, msg models.OutboxMessage, result notifier.SMSResult) error {
+	if msg.Channel != models.OutboxChannelSMS || (result.MessageID == "" && result.Status == "") {
+		return nil
+	}
+
+	outboxID := msg.ID
+	return tx.Create(&models.SMSDelivery{
+		OutboxMessageID: &outboxID,
+		OrderID:         msg.OrderID,
+		PhoneNumber:     msg.Recipient,
+		Provider:        result.Provider,
+		MessageID:       result.MessageID,
+		Cost:            result.Cost,
+		Status:          result.Status,
+		StatusCode:      result.StatusCode,
+	}).Error
+}
+
+func (w *Worker) send(ctx context.Context, msg models.OutboxMessage) (notifier.SMSResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	switch msg.Channel {
+	case models.OutboxChannelSMS:
+		return w.sms.SendSMS(ctx, notifier.SMSMessage{To: msg.Recipient, Body: msg.Body})
+	case models.OutboxChannelEmail:
+		return notifier.SMSResult{}, w.email.SendEmail(ctx, notifier.EmailMessage{
+			To:             msg.Recipient,
+			Subject:        msg.Subject,
+			HTMLBody:       msg.HTMLBody,
+			TextBody:       msg.Body,
+			UnsubscribeURL: msg.UnsubscribeURL,
+		})
+	default:
+		return notifier.SMSResult{}, fmt.Errorf("unknown channel %q", msg.Channel)
+	}
+}
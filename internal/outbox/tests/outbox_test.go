@@ -0,0 +1,170 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/configs"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+	"github.com/Keoroanthony/go-ecommerce/internal/outbox"
+)
+
+var testOutboxConfig = config.OutboxConfig{
+	PollInterval: time.Second,
+	BatchSize:    10,
+	MaxAttempts:  3,
+	BaseBackoff:  time.Minute,
+	MaxBackoff:   10 * time.Minute,
+}
+
+func setupOutboxTestDB(t *testing.T) *gorm.DB {
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect test database: %v", err)
+	}
+
+	if err := testDB.AutoMigrate(&models.OutboxMessage{}, &models.SMSDelivery{}); err != nil {
+		t.Fatalf("failed to auto-migrate models: %v", err)
+	}
+	testDB.Exec("DELETE FROM outbox_messages;")
+	testDB.Exec("DELETE FROM sms_deliveries;")
+
+	return testDB
+}
+
+func newTestWorker(testDB *gorm.DB, sender *notifier.RecordingSender, clock *time.Time) *outbox.Worker {
+	worker := outbox.NewWorker(testDB, sender, sender, testOutboxConfig)
+	worker.Now = func() time.Time { return *clock }
+	return worker
+}
+
+func TestWorkerDeliversQueuedMessages(t *testing.T) {
+	testDB := setupOutboxTestDB(t)
+	sender := notifier.NewRecordingSender()
+
+	orderID := uint(7)
+	assert.NoError(t, outbox.EnqueueSMS(testDB, notifier.SMSMessage{To: "+254700000000", Body: "placed"}, &orderID))
+	assert.NoError(t, outbox.EnqueueEmail(testDB, notifier.EmailMessage{To: "jane@example.com", Subject: "Order", TextBody: "text", HTMLBody: "<p>html</p>"}, &orderID))
+
+	clock := time.Now()
+	worker := newTestWorker(testDB, sender, &clock)
+
+	processed, err := worker.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, processed)
+
+	assert.Equal(t, []notifier.SMSMessage{{To: "+254700000000", Body: "placed"}}, sender.SMS())
+	assert.Equal(t, []notifier.EmailMessage{{To: "jane@example.com", Subject: "Order", TextBody: "text", HTMLBody: "<p>html</p>"}}, sender.Emails())
+
+	var messages []models.OutboxMessage
+	testDB.Order("id").Find(&messages)
+	for _, msg := range messages {
+		assert.Equal(t, models.OutboxStatusSent, msg.Status)
+		assert.Equal(t, 1, msg.Attempts)
+		assert.NotNil(t, msg.SentAt)
+	}
+
+	var deliveries []models.SMSDelivery
+	testDB.Find(&deliveries)
+	if assert.Len(t, deliveries, 1, "only the SMS is recorded as an SMS delivery") {
+		assert.Equal(t, "memory-1", deliveries[0].MessageID)
+		assert.Equal(t, "+254700000000", deliveries[0].PhoneNumber)
+		assert.Equal(t, &orderID, deliveries[0].OrderID)
+		assert.Equal(t, messages[0].ID, *deliveries[0].OutboxMessageID)
+	}
+
+	processed, err = worker.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed, "sent messages are not delivered again")
+}
+
+func TestWorkerKeepsGoingWhenRecordingFails(t *testing.T) {
+	testDB := setupOutboxTestDB(t)
+	sender := notifier.NewRecordingSender()
+
+	assert.NoError(t, outbox.EnqueueSMS(testDB, notifier.SMSMessage{To: "+254700000000", Body: "placed"}, nil))
+	assert.NoError(t, outbox.EnqueueEmail(testDB, notifier.EmailMessage{To: "jane@example.com", Subject: "Order", TextBody: "text"}, nil))
+
+	// Recording the SMS delivery fails; the email after it must still go.
+	testDB.Exec("DROP TABLE sms_deliveries;")
+
+	clock := time.Now()
+	worker := newTestWorker(testDB, sender, &clock)
+
+	processed, err := worker.ProcessDue(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, processed)
+	assert.Len(t, sender.Emails(), 1)
+
+	var email models.OutboxMessage
+	testDB.Where("channel = ?", models.OutboxChannelEmail).First(&email)
+	assert.Equal(t, models.OutboxStatusSent, email.Status)
+}
+
+func TestWorkerRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	testDB := setupOutboxTestDB(t)
+	sender := notifier.NewRecordingSender()
+	sender.SMSError = errors.New("provider down")
+	assert.NoError(t, outbox.EnqueueSMS(testDB, notifier.SMSMessage{To: "+254700000000", Body: "placed"}, nil))
+
+	clock := time.Now()
+	worker := newTestWorker(testDB, sender, &clock)
+
+	var msg models.OutboxMessage
+
+	// First failure: retried after the base backoff.
+	processed, err := worker.ProcessDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	testDB.First(&msg)
+	assert.Equal(t, models.OutboxStatusPending, msg.Status)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, "provider down", msg.LastError)
+	assert.WithinDuration(t, clock.Add(time.Minute), msg.NextAttemptAt, time.Second)
+
+	// Not due yet.
+	processed, _ = worker.ProcessDue(context.Background())
+	assert.Equal(t, 0, processed)
+
+	// Second failure: the delay doubles.
+	clock = clock.Add(time.Minute)
+	processed, _ = worker.ProcessDue(context.Background())
+	assert.Equal(t, 1, processed)
+	testDB.First(&msg)
+	assert.Equal(t, 2, msg.Attempts)
+	assert.WithinDuration(t, clock.Add(2*time.Minute), msg.NextAttemptAt, time.Second)
+
+	// Third failure reaches MaxAttempts.
+	clock = clock.Add(2 * time.Minute)
+	processed, _ = worker.ProcessDue(context.Background())
+	assert.Equal(t, 1, processed)
+	testDB.First(&msg)
+	assert.Equal(t, models.OutboxStatusDead, msg.Status)
+	assert.Equal(t, 3, msg.Attempts)
+
+	clock = clock.Add(time.Hour)
+	processed, _ = worker.ProcessDue(context.Background())
+	assert.Equal(t, 0, processed, "dead messages are left alone")
+	assert.Empty(t, sender.SMS())
+
+	var deliveries int64
+	testDB.Model(&models.SMSDelivery{}).Count(&deliveries)
+	assert.Zero(t, deliveries, "attempts that never reached the provider leave no delivery record")
+}
+
+func TestWorkerBackoff(t *testing.T) {
+	worker := outbox.NewWorker(nil, nil, nil, testOutboxConfig)
+
+	assert.Equal(t, time.Minute, worker.Backoff(1))
+	assert.Equal(t, 2*time.Minute, worker.Backoff(2))
+	assert.Equal(t, 8*time.Minute, worker.Backoff(4))
+	assert.Equal(t, 10*time.Minute, worker.Backoff(5))
+	assert.Equal(t, 10*time.Minute, worker.Backoff(60))
+}
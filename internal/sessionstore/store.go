@@ -0,0 +1,270 @@
+// Package sessionstore keeps gin sessions in the database instead of in the
+// cookie, so they can be listed, expired and revoked on the server.
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+)
+
+// CustomerIDKey and UserIDKey are the session values auth stores the
+// signed-in customer and staff user under. The store copies them to
+// Session.CustomerID and Session.UserID so their sessions can be found.
+const (
+	CustomerIDKey = "customer_id"
+	UserIDKey     = "user_id"
+)
+
+// lastSeenInterval limits how often reading a session writes its
+// LastSeenAt, so busy sessions don't cost a write per request.
+const lastSeenInterval = time.Minute
+
+const maxUserAgentLength = 512
+
+// Store implements sessions.Store on top of the sessions table. The cookie
+// only holds a random token, signed with the configured keys.
+type Store struct {
+	db      *gorm.DB
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+	Now     func() time.Time
+}
+
+// New returns a store that keeps sessions in database for maxAge. keyPairs
+// are passed to securecookie, as for cookie.NewStore.
+func New(database *gorm.DB, maxAge time.Duration, keyPairs ...[]byte) *Store {
+	s := &Store{
+		db:     database,
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Now:    time.Now,
+	}
+	s.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return s
+}
+
+// Options sets the cookie options used for new sessions.
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok && s.options.MaxAge > 0 {
+			sc.MaxAge(s.options.MaxAge)
+		}
+	}
+}
+
+// Get returns the named session for r, loading it once per request.
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by r's cookie. A missing, tampered, expired
+// or revoked session gives a new empty one.
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	now := s.Now()
+	var row models.Session
+	err = s.db.Where("token_hash = ? AND expires_at > ?", models.HashSessionToken(token), now).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	if err := decodeValues(row.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+
+	if now.Sub(row.LastSeenAt) >= lastSeenInterval {
+		if err := s.db.Model(&row).UpdateColumn("last_seen_at", now).Error; err != nil {
+			log.Printf("sessionstore: updating last seen: %v", err)
+		}
+	}
+
+	return session, nil
+}
+
+// Save writes the session and its cookie. A negative MaxAge deletes it.
+// When the signed-in customer or user changes, e.g. on login, the session
+// gets a new token so one obtained beforehand cannot be used afterwards.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.db.Where("token_hash = ?", models.HashSessionToken(session.ID)).Delete(&models.Session{}).Error; err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := encodeValues(session.Values)
+	if err != nil {
+		return err
+	}
+
+	now := s.Now()
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		// A browser-session cookie still needs a server-side limit.
+		maxAge = s.options.MaxAge
+	}
+
+	customerID := idValue(session.Values, CustomerIDKey)
+	userID := idValue(session.Values, UserIDKey)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var row models.Session
+		found := false
+		if session.ID != "" {
+			err := tx.Where("token_hash = ?", models.HashSessionToken(session.ID)).First(&row).Error
+			switch {
+			case err == nil:
+				found = true
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			}
+		}
+
+		if found && (!sameID(row.CustomerID, customerID) || !sameID(row.UserID, userID)) {
+			if err := tx.Delete(&row).Error; err != nil {
+				return err
+			}
+			found = false
+		}
+
+		if !found {
+			token, err := newToken()
+			if err != nil {
+				return err
+			}
+			session.ID = token
+			row = models.Session{
+				TokenHash: models.HashSessionToken(token),
+				UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+				CreatedAt: now,
+			}
+		}
+
+		row.CustomerID = customerID
+		row.UserID = userID
+		row.Data = data
+		row.LastSeenAt = now
+		row.ExpiresAt = now.Add(time.Duration(maxAge) * time.Second)
+
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// PurgeExpired deletes sessions past their expiry and returns how many.
+func (s *Store) PurgeExpired() (int64, error) {
+	result := s.db.Where("expires_at <= ?", s.Now()).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// RunPurge calls PurgeExpired every interval until ctx is cancelled.
+func (s *Store) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeExpired(); err != nil {
+			log.Printf("sessionstore: purging expired sessions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func idValue(values map[interface{}]interface{}, key string) *uint {
+	if id, ok := values[key].(uint); ok && id != 0 {
+		return &id
+	}
+	return nil
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func encodeValues(values map[interface{}]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeValues(data []byte, values *map[interface{}]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(values)
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
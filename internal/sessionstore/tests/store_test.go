@@ -0,0 +1,193 @@
+package sessionstore_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/sessionstore"
+)
+
+type storeTestApp struct {
+	store  *sessionstore.Store
+	db     *gorm.DB
+	server *httptest.Server
+	client *http.Client
+	now    time.Time
+}
+
+func setupStoreTest(t *testing.T) *storeTestApp {
+	gin.SetMode(gin.TestMode)
+
+	testDB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+	if err := testDB.AutoMigrate(&models.Session{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	app := &storeTestApp{db: testDB, now: time.Now()}
+	app.store = sessionstore.New(testDB, time.Hour, []byte("test-session-secret"))
+	app.store.Now = func() time.Time { return app.now }
+
+	r := gin.New()
+	r.Use(sessions.Sessions("gosess", app.store))
+	r.POST("/set", func(c *gin.Context) {
+		sess := sessions.Default(c)
+		sess.Set("greeting", c.Query("value"))
+		sess.Save()
+	})
+	r.POST("/login", func(c *gin.Context) {
+		sess := sessions.Default(c)
+		sess.Set(sessionstore.CustomerIDKey, uint(7))
+		sess.Save()
+	})
+	r.POST("/logout", func(c *gin.Context) {
+		sess := sessions.Default(c)
+		sess.Clear()
+		sess.Options(sessions.Options{Path: "/", MaxAge: -1})
+		sess.Save()
+	})
+	r.GET("/get", func(c *gin.Context) {
+		greeting, _ := sessions.Default(c).Get("greeting").(string)
+		c.String(http.StatusOK, greeting)
+	})
+
+	app.server = httptest.NewServer(r)
+	t.Cleanup(app.server.Close)
+
+	jar, _ := cookiejar.New(nil)
+	app.client = &http.Client{Jar: jar}
+
+	return app
+}
+
+func (a *storeTestApp) do(t *testing.T, method, path string) string {
+	req, _ := http.NewRequest(method, a.server.URL+path, nil)
+	req.Header.Set("User-Agent", "store-test/1.0")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func (a *storeTestApp) cookie() string {
+	u, _ := url.Parse(a.server.URL)
+	for _, c := range a.client.Jar.Cookies(u) {
+		if c.Name == "gosess" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func (a *storeTestApp) sessions(t *testing.T) []models.Session {
+	var rows []models.Session
+	if err := a.db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatalf("listing sessions: %v", err)
+	}
+	return rows
+}
+
+func TestStore(t *testing.T) {
+	t.Run("Keeps values on the server", func(t *testing.T) {
+		app := setupStoreTest(t)
+
+		app.do(t, http.MethodPost, "/set?value=hello")
+		assert.Equal(t, "hello", app.do(t, http.MethodGet, "/get"))
+
+		rows := app.sessions(t)
+		assert.Len(t, rows, 1)
+		assert.Equal(t, "store-test/1.0", rows[0].UserAgent)
+		assert.Nil(t, rows[0].CustomerID)
+		assert.WithinDuration(t, app.now.Add(time.Hour), rows[0].ExpiresAt, time.Second)
+		assert.NotContains(t, app.cookie(), rows[0].TokenHash)
+	})
+
+	t.Run("Ignores tampered cookies", func(t *testing.T) {
+		app := setupStoreTest(t)
+		app.do(t, http.MethodPost, "/set?value=hello")
+
+		u, _ := url.Parse(app.server.URL)
+		app.client.Jar.SetCookies(u, []*http.Cookie{{Name: "gosess", Value: app.cookie() + "x", Path: "/"}})
+
+		assert.Equal(t, "", app.do(t, http.MethodGet, "/get"))
+	})
+
+	t.Run("Issues a new token when the customer signs in", func(t *testing.T) {
+		app := setupStoreTest(t)
+		app.do(t, http.MethodPost, "/set?value=hello")
+		before := app.cookie()
+
+		app.do(t, http.MethodPost, "/login")
+
+		assert.NotEqual(t, before, app.cookie())
+		rows := app.sessions(t)
+		assert.Len(t, rows, 1)
+		if assert.NotNil(t, rows[0].CustomerID) {
+			assert.Equal(t, uint(7), *rows[0].CustomerID)
+		}
+		assert.Equal(t, "hello", app.do(t, http.MethodGet, "/get"))
+	})
+
+	t.Run("Ends sessions that are deleted, expired or logged out", func(t *testing.T) {
+		app := setupStoreTest(t)
+
+		app.do(t, http.MethodPost, "/set?value=hello")
+		app.db.Where("1 = 1").Delete(&models.Session{})
+		assert.Equal(t, "", app.do(t, http.MethodGet, "/get"))
+
+		app.do(t, http.MethodPost, "/set?value=hello")
+		app.now = app.now.Add(2 * time.Hour)
+		assert.Equal(t, "", app.do(t, http.MethodGet, "/get"))
+
+		app.do(t, http.MethodPost, "/set?value=hello")
+		app.do(t, http.MethodPost, "/logout")
+		assert.Equal(t, "", app.do(t, http.MethodGet, "/get"))
+		assert.Equal(t, "", app.cookie())
+	})
+
+	t.Run("Records when a session was last used", func(t *testing.T) {
+		app := setupStoreTest(t)
+		app.do(t, http.MethodPost, "/set?value=hello")
+		created := app.now
+
+		app.now = created.Add(10 * time.Second)
+		app.do(t, http.MethodGet, "/get")
+		assert.WithinDuration(t, created, app.sessions(t)[0].LastSeenAt, time.Millisecond)
+
+		app.now = created.Add(5 * time.Minute)
+		app.do(t, http.MethodGet, "/get")
+		assert.WithinDuration(t, app.now, app.sessions(t)[0].LastSeenAt, time.Millisecond)
+	})
+
+	t.Run("Purges expired sessions", func(t *testing.T) {
+		app := setupStoreTest(t)
+		app.do(t, http.MethodPost, "/set?value=hello")
+
+		purged, err := app.store.PurgeExpired()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		app.now = app.now.Add(2 * time.Hour)
+		purged, err = app.store.PurgeExpired()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.Empty(t, app.sessions(t))
+	})
+}
@@ -1,31 +1,95 @@
 package utils
 
 import (
+    "fmt"
+
     "github.com/Keoroanthony/go-ecommerce/internal/db"
     "github.com/Keoroanthony/go-ecommerce/internal/models"
 )
 
+// descendantIDsQuery walks the tree below a category in a single round trip.
+// It is plain SQL:1999 so it runs unchanged on Postgres and SQLite, and
+// UNION (rather than UNION ALL) stops the recursion should a cycle ever
+// make it into the data.
+const descendantIDsQuery = `
+WITH RECURSIVE descendants(id) AS (
+    SELECT id FROM categories WHERE parent_id = ?
+    UNION
+    SELECT categories.id FROM categories JOIN descendants ON categories.parent_id = descendants.id
+)
+SELECT id FROM descendants WHERE id <> ?`
+
+// GetAllCategoryIDs returns rootID followed by the IDs of every category
+// beneath it.
 func GetAllCategoryIDs(rootID uint) ([]uint, error) {
-    var result []uint
-    result = append(result, rootID)
+    var descendantIDs []uint
+    if err := db.DB.Raw(descendantIDsQuery, rootID, rootID).Scan(&descendantIDs).Error; err != nil {
+        return nil, err
+    }
+
+    return append([]uint{rootID}, descendantIDs...), nil
+}
 
-    var queue = []uint{rootID}
+// BuildCategoryTree nests a flat list of categories through their Children
+// field. Categories whose parent is not in the list become roots, so the
+// function works for both the full catalogue and a single subtree. Roots are
+// depth 1; a maxDepth of 0 or less means no limit.
+func BuildCategoryTree(categories []models.Category, maxDepth int) []models.Category {
+    present := make(map[uint]bool, len(categories))
+    for _, category := range categories {
+        present[category.ID] = true
+    }
 
-    for len(queue) > 0 {
-        current := queue[0]
-        queue = queue[1:]
+    childrenOf := make(map[uint][]models.Category)
+    var roots []models.Category
 
-        var children []models.Category
-        err := db.DB.Where("parent_id = ?", current).Find(&children).Error
-        if err != nil {
-            return nil, err
+    for _, category := range categories {
+        if category.ParentID == nil || !present[*category.ParentID] {
+            roots = append(roots, category)
+            continue
+        }
+        childrenOf[*category.ParentID] = append(childrenOf[*category.ParentID], category)
+    }
+
+    var attach func(nodes []models.Category, depth int) []models.Category
+    attach = func(nodes []models.Category, depth int) []models.Category {
+        for i := range nodes {
+            nodes[i].Children = []models.Category{}
+            if maxDepth > 0 && depth >= maxDepth {
+                continue
+            }
+            nodes[i].Children = attach(childrenOf[nodes[i].ID], depth+1)
+        }
+        if nodes == nil {
+            return []models.Category{}
+        }
+        return nodes
+    }
+
+    return attach(roots, 1)
+}
+
+// GetCategoryPath returns the chain of categories from the top-level root
+// down to and including the category with the given ID.
+func GetCategoryPath(categoryID uint) ([]models.Category, error) {
+    var path []models.Category
+    seen := make(map[uint]bool)
+
+    currentID := &categoryID
+    for currentID != nil {
+        if seen[*currentID] {
+            return nil, fmt.Errorf("category %d has a cyclic parent chain", categoryID)
         }
+        seen[*currentID] = true
 
-        for _, child := range children {
-            result = append(result, child.ID)
-            queue = append(queue, child.ID)
+        var category models.Category
+        if err := db.DB.First(&category, *currentID).Error; err != nil {
+            return nil, err
         }
+
+        path = append([]models.Category{category}, path...)
+        currentID = category.ParentID
     }
 
-    return result, nil
+    return path, nil
 }
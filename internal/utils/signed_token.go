@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed or whose
+// signature does not match.
+var ErrInvalidToken = errors.New("invalid token")
+
+// SignToken encodes payload as JSON and appends an HMAC-SHA256 signature,
+// giving a URL-safe "<payload>.<signature>" string that can be handed out
+// in links and later trusted without a database lookup.
+func SignToken(secret []byte, payload interface{}) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, encoded)), nil
+}
+
+// VerifyToken checks token's signature and decodes its payload into dest.
+func VerifyToken(secret []byte, token string, dest interface{}) error {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, tokenSignature(secret, encoded)) {
+		return ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+func tokenSignature(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
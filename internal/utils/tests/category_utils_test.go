@@ -0,0 +1,147 @@
+package utils_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/db"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+)
+
+// setupCategoryTree seeds a tree with the given fan-out and depth below a
+// single root and returns the root ID and the total number of categories.
+func setupCategoryTree(tb testing.TB, fanOut, depth int) (uint, int) {
+	testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to connect test database: " + err.Error())
+	}
+
+	if err := testDB.AutoMigrate(&models.Category{}); err != nil {
+		panic("failed to auto-migrate models: " + err.Error())
+	}
+
+	testDB.Exec("DELETE FROM categories;")
+
+	originalDB := db.DB
+	db.SetTestDB(testDB)
+	tb.Cleanup(func() {
+		db.SetTestDB(originalDB)
+	})
+
+	root := models.Category{Name: "root"}
+	testDB.Create(&root)
+	total := 1
+
+	level := []uint{root.ID}
+	for d := 0; d < depth; d++ {
+		var next []uint
+		for _, parentID := range level {
+			parentID := parentID
+			for i := 0; i < fanOut; i++ {
+				child := models.Category{Name: fmt.Sprintf("cat-%d-%d", parentID, i), ParentID: &parentID}
+				testDB.Create(&child)
+				next = append(next, child.ID)
+				total++
+			}
+		}
+		level = next
+	}
+
+	return root.ID, total
+}
+
+// getAllCategoryIDsBFS is the previous one-query-per-node implementation,
+// kept here as the baseline for the benchmarks below.
+func getAllCategoryIDsBFS(rootID uint) ([]uint, error) {
+	result := []uint{rootID}
+	queue := []uint{rootID}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		var children []models.Category
+		if err := db.DB.Where("parent_id = ?", current).Find(&children).Error; err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			result = append(result, child.ID)
+			queue = append(queue, child.ID)
+		}
+	}
+
+	return result, nil
+}
+
+func TestGetAllCategoryIDs(t *testing.T) {
+	rootID, total := setupCategoryTree(t, 3, 3)
+
+	t.Run("Returns the root followed by every descendant", func(t *testing.T) {
+		ids, err := utils.GetAllCategoryIDs(rootID)
+
+		assert.NoError(t, err)
+		assert.Len(t, ids, total)
+		assert.Equal(t, rootID, ids[0])
+
+		expected, err := getAllCategoryIDsBFS(rootID)
+		assert.NoError(t, err)
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+		assert.Equal(t, expected, ids)
+	})
+
+	t.Run("Returns only the root for a leaf category", func(t *testing.T) {
+		var leaf models.Category
+		db.DB.Order("id DESC").First(&leaf)
+
+		ids, err := utils.GetAllCategoryIDs(leaf.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{leaf.ID}, ids)
+	})
+
+	t.Run("Terminates on a cyclic parent chain", func(t *testing.T) {
+		a := models.Category{Name: "cycle-a"}
+		db.DB.Create(&a)
+		b := models.Category{Name: "cycle-b", ParentID: &a.ID}
+		db.DB.Create(&b)
+		db.DB.Model(&a).Update("parent_id", b.ID)
+
+		ids, err := utils.GetAllCategoryIDs(a.ID)
+
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []uint{a.ID, b.ID}, ids)
+	})
+}
+
+// 1,555 categories: 6 children per node, 4 levels below the root. Run with
+// go test ./internal/utils/tests -run ^$ -bench GetAllCategoryIDs
+func BenchmarkGetAllCategoryIDs(b *testing.B) {
+	rootID, _ := setupCategoryTree(b, 6, 4)
+
+	b.Run("RecursiveCTE", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := utils.GetAllCategoryIDs(rootID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("BFS", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := getAllCategoryIDsBFS(rootID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package utils_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Keoroanthony/go-ecommerce/internal/utils"
+)
+
+type tokenPayload struct {
+	CustomerID uint   `json:"c"`
+	Channel    string `json:"ch"`
+}
+
+func TestSignedToken(t *testing.T) {
+	secret := []byte("test-secret")
+
+	token, err := utils.SignToken(secret, tokenPayload{CustomerID: 42, Channel: "email"})
+	assert.NoError(t, err)
+	assert.NotContains(t, token, "=")
+
+	t.Run("Round-trips the payload", func(t *testing.T) {
+		var decoded tokenPayload
+		assert.NoError(t, utils.VerifyToken(secret, token, &decoded))
+		assert.Equal(t, tokenPayload{CustomerID: 42, Channel: "email"}, decoded)
+	})
+
+	t.Run("Rejects a different secret", func(t *testing.T) {
+		var decoded tokenPayload
+		err := utils.VerifyToken([]byte("other-secret"), token, &decoded)
+		assert.True(t, errors.Is(err, utils.ErrInvalidToken))
+	})
+
+	t.Run("Rejects a tampered payload", func(t *testing.T) {
+		forged, _ := utils.SignToken([]byte("other-secret"), tokenPayload{CustomerID: 7, Channel: "email"})
+		_, signature, _ := strings.Cut(token, ".")
+		payload, _, _ := strings.Cut(forged, ".")
+
+		var decoded tokenPayload
+		err := utils.VerifyToken(secret, payload+"."+signature, &decoded)
+		assert.True(t, errors.Is(err, utils.ErrInvalidToken))
+	})
+
+	t.Run("Rejects malformed tokens", func(t *testing.T) {
+		var decoded tokenPayload
+		for _, bad := range []string{"", "no-dot", "a.b", token + "x"} {
+			assert.ErrorIs(t, utils.VerifyToken(secret, bad, &decoded), utils.ErrInvalidToken, bad)
+		}
+	})
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
-    "os"
+    "context"
+    "log"
+    "time"
 
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 
+	"github.com/Keoroanthony/go-ecommerce/configs"
 	"github.com/Keoroanthony/go-ecommerce/internal/auth"
 	"github.com/Keoroanthony/go-ecommerce/internal/db"
 	"github.com/Keoroanthony/go-ecommerce/internal/handlers"
+	"github.com/Keoroanthony/go-ecommerce/internal/idempotency"
+	"github.com/Keoroanthony/go-ecommerce/internal/models"
+	"github.com/Keoroanthony/go-ecommerce/internal/notifier"
+	"github.com/Keoroanthony/go-ecommerce/internal/outbox"
+	"github.com/Keoroanthony/go-ecommerce/internal/sessionstore"
 )
 
 func main() {
@@ -17,36 +24,132 @@ func main() {
     db.Init()
     auth.Init()
 
+    notifierCfg := config.LoadNotifierConfig()
+    smsSender, err := notifier.NewSMSSender(notifierCfg)
+    if err != nil {
+        log.Fatalf("SMS notifier init error: %v", err)
+    }
+    emailSender, err := notifier.NewEmailSender(notifierCfg)
+    if err != nil {
+        log.Fatalf("Email notifier init error: %v", err)
+    }
+    templates, err := notifier.LoadTemplates(notifierCfg.TemplateDir, notifierCfg.DefaultLocale)
+    if err != nil {
+        log.Fatalf("Notification templates error: %v", err)
+    }
+    handlers.SetTemplates(templates)
+    handlers.SetUnsubscribeLinks(notifierCfg.PublicBaseURL, notifierCfg.UnsubscribeSecret)
+    handlers.SetDefaultPhoneCountry(config.LoadDefaultPhoneCountry())
+
+    worker := outbox.NewWorker(db.DB, smsSender, emailSender, config.LoadOutboxConfig())
+    go worker.Run(context.Background())
+
     r := gin.Default()
 
     // ── session store ──
-	store := cookie.NewStore([]byte(getEnv("SESSION_SECRET", "change-me")))
+	sessionCfg := config.LoadSessionConfig()
+	if !sessionCfg.IsDevelopment() && (sessionCfg.Secret == "" || sessionCfg.Secret == config.DefaultSessionSecret) {
+		log.Fatalf("SESSION_SECRET must be set to a non-default value unless APP_ENV=development")
+	}
+	callbackToken := config.LoadAfricaTalkingConfig().CallbackToken
+	if !sessionCfg.IsDevelopment() && callbackToken == "" {
+		log.Fatalf("AT_CALLBACK_TOKEN must be set unless APP_ENV=development")
+	}
+	store := sessionstore.New(db.DB, sessionCfg.MaxAge, []byte(sessionCfg.Secret))
+	go store.RunPurge(context.Background(), time.Hour)
+	go idempotency.RunPurge(context.Background(), db.DB, time.Hour)
 	r.Use(sessions.Sessions("gosess", store))
 
     // ── public endpoints ──
 	r.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
+	r.GET("/auth/providers", auth.ListProviders)
 	r.GET("/auth/login", auth.Login)
+	r.GET("/auth/login/:provider", auth.Login)
 	r.GET("/auth/callback", auth.Callback)
+	r.GET("/auth/callback/:provider", auth.Callback)
+	r.POST("/auth/logout", auth.Logout)
+	r.GET("/unsubscribe", handlers.ConfirmUnsubscribe)
+	r.POST("/unsubscribe", handlers.Unsubscribe)
+	r.POST("/callbacks/sms/delivery", handlers.SMSDeliveryCallback(callbackToken))
 
     // ── protected API ──
     api := r.Group("/api")
     api.Use(auth.RequireAuth())
+
+    // Staff roles; admins pass every check.
+    catalogManagers := auth.RequireRole(models.RoleCatalogManager)
+    stockKeepers := auth.RequireRole(models.RoleCatalogManager, models.RoleFulfilment)
+    orderHandlers := auth.RequireRole(models.RoleFulfilment, models.RoleSupport)
+    supportStaff := auth.RequireRole(models.RoleSupport)
+    admins := auth.RequireRole(models.RoleAdmin)
+
+    // Routes open to API keys (X-API-Key) and the scope each needs; every
+    // other route turns keys away.
+    auth.SetAPIKeyScopes(map[string]string{
+        "GET /api/categories":                       models.ScopeCatalogRead,
+        "GET /api/categories/tree":                  models.ScopeCatalogRead,
+        "GET /api/categories/:id":                   models.ScopeCatalogRead,
+        "POST /api/categories":                      models.ScopeCatalogWrite,
+        "PUT /api/categories/:id":                   models.ScopeCatalogWrite,
+        "PATCH /api/categories/:id":                 models.ScopeCatalogWrite,
+        "GET /api/products":                         models.ScopeCatalogRead,
+        "GET /api/products/:id":                     models.ScopeCatalogRead,
+        "POST /api/products":                        models.ScopeCatalogWrite,
+        "PUT /api/products/:id":                     models.ScopeCatalogWrite,
+        "PATCH /api/products/:id":                   models.ScopeCatalogWrite,
+        "POST /api/products/:id/stock/restock":      models.ScopeInventoryWrite,
+        "POST /api/products/:id/stock/adjustments":  models.ScopeInventoryWrite,
+        "GET /api/products/:id/stock/movements":     models.ScopeInventoryRead,
+        "GET /api/admin/orders":                     models.ScopeOrdersRead,
+        "GET /api/admin/orders/:id":                 models.ScopeOrdersRead,
+        "PATCH /api/orders/:id/status":              models.ScopeOrdersWrite,
+    })
     {
-        api.POST("/categories", handlers.CreateCategory)
-        api.POST("/products", handlers.CreateProduct)
+        api.GET("/me", handlers.GetProfile)
+        api.PATCH("/me", handlers.UpdateProfile)
+        api.GET("/me/sessions", handlers.ListSessions)
+        api.DELETE("/me/sessions", handlers.RevokeOtherSessions)
+        api.DELETE("/me/sessions/:id", handlers.RevokeSession)
+        api.GET("/me/identities", handlers.ListIdentities)
+        api.DELETE("/me/identities/:id", handlers.UnlinkIdentity)
+        api.GET("/me/notifications", handlers.GetNotificationPreferences)
+        api.PUT("/me/notifications", handlers.UpdateNotificationPreferences)
+        api.POST("/categories", catalogManagers, handlers.CreateCategory)
+        api.GET("/categories", handlers.ListCategories)
+        api.GET("/categories/tree", handlers.GetCategoryTree)
+        api.GET("/categories/:id", handlers.GetCategory)
+        api.GET("/categories/:id/breadcrumbs", handlers.GetCategoryBreadcrumbs)
+        api.PUT("/categories/:id", catalogManagers, handlers.UpdateCategory)
+        api.PATCH("/categories/:id", catalogManagers, handlers.PatchCategory)
+        api.DELETE("/categories/:id", catalogManagers, handlers.DeleteCategory)
+        api.POST("/products", catalogManagers, handlers.CreateProduct)
         api.GET("/products/average", handlers.GetAveragePrice)
-        api.POST("/orders", handlers.CreateOrder)
+        api.GET("/products", handlers.ListProducts)
+        api.GET("/products/:id", handlers.GetProduct)
+        api.PUT("/products/:id", catalogManagers, handlers.UpdateProduct)
+        api.PATCH("/products/:id", catalogManagers, handlers.PatchProduct)
+        api.DELETE("/products/:id", catalogManagers, handlers.DeleteProduct)
+        api.POST("/products/:id/stock/restock", stockKeepers, handlers.RestockProduct)
+        api.POST("/products/:id/stock/adjustments", stockKeepers, handlers.AdjustProductStock)
+        api.GET("/products/:id/stock/movements", stockKeepers, handlers.ListStockMovements)
+        api.POST("/orders", handlers.Idempotency(), handlers.CreateOrder)
+        api.GET("/orders", handlers.ListOrders)
+        api.GET("/orders/:id", handlers.GetOrder)
+        api.PATCH("/orders/:id/status", orderHandlers, handlers.UpdateOrderStatus)
+        api.GET("/admin/orders", orderHandlers, handlers.ListAllOrders)
+        api.GET("/admin/orders/:id", orderHandlers, handlers.GetAnyOrder)
+        api.GET("/admin/outbox", supportStaff, handlers.ListOutboxMessages)
+        api.POST("/admin/outbox/:id/redrive", supportStaff, handlers.RedriveOutboxMessage)
+        api.GET("/admin/sms-deliveries", supportStaff, handlers.ListSMSDeliveries)
+        api.GET("/admin/users", admins, handlers.ListStaffUsers)
+        api.POST("/admin/users", admins, handlers.CreateStaffUser)
+        api.PUT("/admin/users/:id/role", admins, handlers.UpdateStaffRole)
+        api.DELETE("/admin/users/:id", admins, handlers.DeleteStaffUser)
+        api.GET("/admin/api-keys", admins, handlers.ListAPIKeys)
+        api.POST("/admin/api-keys", admins, handlers.CreateAPIKey)
+        api.DELETE("/admin/api-keys/:id", admins, handlers.RevokeAPIKey)
+        api.POST("/admin/api-keys/:id/rotate", admins, handlers.RotateAPIKey)
     }
 
     r.Run(":8080")
 }
-
-
-func getEnv(key, fallback string) string {
-
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-
-	return fallback
-}
\ No newline at end of file